@@ -3,9 +3,12 @@ package builder
 import (
 	"context"
 	"fmt"
+	"mime"
 	"reflect"
+	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/schmurfy/chipi/request"
 	"github.com/schmurfy/chipi/schema"
 	"github.com/schmurfy/chipi/shared"
 	"github.com/schmurfy/chipi/wrapper"
@@ -19,23 +22,53 @@ func (b *Builder) generateBodyDoc(ctx context.Context, swagger *openapi3.T, op *
 			return err
 		}
 
-		// check that a body decoder is available
-		if _, ok := requestObject.(wrapper.BodyDecoder); !ok {
-			return fmt.Errorf("%s must implement BodyDecoder", requestObjectType.Name())
-		}
+		body := openapi3.NewRequestBody()
+		bodyRef := &openapi3.RequestBodyRef{Value: body}
+		body.Content = openapi3.Content{}
 
-		contentType, found := bodyField.Tag.Lookup("content-type")
-		if !found {
-			contentType = "application/json"
+		if decoders, ok := requestObject.(wrapper.BodyDecodersInterface); ok {
+			// one entry for each accepted content type
+			list := decoders.BodyDecoders()
+			if len(list) == 0 {
+				return fmt.Errorf("%s must return at least one BodyDecoder", requestObjectType.Name())
+			}
+
+			for _, contentType := range shared.SortedContentTypes(list) {
+				if !wrapper.IsBodyDecoder(list[contentType]) {
+					return fmt.Errorf("%s: decoder for %s must implement BodyDecoder", requestObjectType.Name(), contentType)
+				}
+
+				body.Content[contentType] = bodyMediaType(swagger, contentType, bodySchema, bodyField.Type, list[contentType])
+			}
+		} else {
+			// check that a body decoder is available
+			decoder := requestObject
+			if !wrapper.IsBodyDecoder(decoder) {
+				decoder = b.config.BodyDecoder
+			}
+
+			if !wrapper.IsBodyDecoder(decoder) {
+				return fmt.Errorf("%s must implement BodyDecoder", requestObjectType.Name())
+			}
+
+			contentType, found := bodyField.Tag.Lookup("content-type")
+			if !found {
+				contentType = "application/json"
+			}
+
+			body.Content[contentType] = bodyMediaType(swagger, contentType, bodySchema, bodyField.Type, decoder)
 		}
 
-		body := openapi3.NewRequestBody()
-		bodyRef := &openapi3.RequestBodyRef{Value: body}
+		// limits enforced when decoding
+		maxBodySize, err := wrapper.MaxBodySize(requestObject, b.config.MaxBodySize)
+		if err != nil {
+			return err
+		}
 
-		body.Content = openapi3.Content{
-			contentType: &openapi3.MediaType{
-				Schema: bodySchema,
-			},
+		if maxBodySize > 0 {
+			body.Extensions = map[string]interface{}{
+				"x-max-size": maxBodySize,
+			}
 		}
 
 		tag := schema.ParseJsonTag(bodyField)
@@ -53,3 +86,137 @@ func (b *Builder) generateBodyDoc(ctx context.Context, swagger *openapi3.T, op *
 
 	return nil
 }
+
+func bodyMediaType(swagger *openapi3.T, contentType string, bodySchema *openapi3.SchemaRef, typ reflect.Type, decoder interface{}) *openapi3.MediaType {
+	ret := &openapi3.MediaType{
+		Schema: bodySchema,
+	}
+
+	// the decoder options (ex: x-max-depth)
+	if documented, ok := decoder.(wrapper.BodyExtensionsInterface); ok {
+		if extensions := documented.BodyExtensions(); len(extensions) > 0 {
+			ret.Extensions = extensions
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ret
+	}
+
+	switch mediaType {
+	case "multipart/form-data":
+		ret.Schema = formSchema(swagger, bodySchema, typ)
+		ret.Encoding = multipartEncoding(typ)
+
+	case "application/x-www-form-urlencoded":
+		ret.Schema = formSchema(swagger, bodySchema, typ)
+	}
+
+	return ret
+}
+
+// formSchema returns a copy of the body schema with the properties renamed
+// by their form tag (ex: `form:"file"`), bodySchema is returned as is if
+// there is none.
+func formSchema(swagger *openapi3.T, bodySchema *openapi3.SchemaRef, typ reflect.Type) *openapi3.SchemaRef {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return bodySchema
+	}
+
+	renamed := map[string]string{}
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+
+		tag := schema.ParseJsonTag(f)
+		if name := request.FormFieldName(f); !tag.GetIgnored() && (name != tag.Name) {
+			renamed[tag.Name] = name
+		}
+	}
+
+	if len(renamed) == 0 {
+		return bodySchema
+	}
+
+	value := bodySchema.Value
+	if (value == nil) && (swagger.Components != nil) {
+		if component := swagger.Components.Schemas[strings.TrimPrefix(bodySchema.Ref, "#/components/schemas/")]; component != nil {
+			value = component.Value
+		}
+	}
+
+	if value == nil {
+		return bodySchema
+	}
+
+	ret := *value
+	ret.Properties = openapi3.Schemas{}
+	for name, property := range value.Properties {
+		if newName, found := renamed[name]; found {
+			name = newName
+		}
+
+		// ignored by the form decoder
+		if name != "-" {
+			ret.Properties[name] = property
+		}
+	}
+
+	ret.Required = nil
+	for _, name := range value.Required {
+		if newName, found := renamed[name]; found {
+			name = newName
+		}
+
+		if name != "-" {
+			ret.Required = append(ret.Required, name)
+		}
+	}
+
+	return openapi3.NewSchemaRef("", &ret)
+}
+
+// describe how the files of a multipart body are sent, the content type
+// can be set with a tag (ex: `content-type:"image/png"`)
+func multipartEncoding(typ reflect.Type) map[string]*openapi3.Encoding {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	ret := map[string]*openapi3.Encoding{}
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous || !request.IsFileType(f.Type) {
+			continue
+		}
+
+		name := request.FormFieldName(f)
+		if name == "-" {
+			continue
+		}
+
+		contentType, found := f.Tag.Lookup("content-type")
+		if !found {
+			contentType = "application/octet-stream"
+		}
+
+		ret[name] = &openapi3.Encoding{
+			ContentType: contentType,
+		}
+	}
+
+	if len(ret) == 0 {
+		return nil
+	}
+
+	return ret
+}
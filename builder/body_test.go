@@ -2,6 +2,9 @@ package builder
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"reflect"
 	"testing"
@@ -46,6 +49,72 @@ type bodyTestWithDecoderRequest struct {
 	}
 }
 
+type bodyTestMultipartRequest struct {
+	noopHandler
+
+	Path struct {
+	} `example:"/pet"`
+
+	request.MultipartBodyDecoder
+	Body struct {
+		Name    string                  `json:"name"`
+		Avatar  *multipart.FileHeader   `json:"avatar" content-type:"image/png"`
+		Photos  []*multipart.FileHeader `json:"photos"`
+		License io.Reader
+		Cover   []byte `json:"cover" form:"cover_image"`
+	} `content-type:"multipart/form-data"`
+}
+
+type bodyTestMultipleDecodersRequest struct {
+	noopHandler
+
+	Path struct {
+	} `example:"/pet"`
+
+	Body struct {
+		Name string `json:"name"`
+	}
+
+	invalid bool
+}
+
+func (r *bodyTestMultipleDecodersRequest) BodyDecoders() map[string]interface{} {
+	ret := map[string]interface{}{
+		"application/json":                  &request.JsonBodyDecoder{},
+		"application/x-www-form-urlencoded": &request.FormBodyDecoder{},
+	}
+
+	if r.invalid {
+		ret["application/msgpack"] = "not a decoder"
+	}
+
+	return ret
+}
+
+type bodyTestLimitedRequest struct {
+	noopHandler
+
+	Path struct {
+	} `example:"/pet"`
+
+	request.JsonBodyDecoder
+	Body struct {
+		Name string
+	} `max-size:"1KB"`
+}
+
+type bodyTestInvalidLimitRequest struct {
+	noopHandler
+
+	Path struct {
+	} `example:"/pet"`
+
+	request.JsonBodyDecoder
+	Body struct {
+		Name string
+	} `max-size:"big"`
+}
+
 func TestBodyGenerator(t *testing.T) {
 	g := goblin.Goblin(t)
 
@@ -79,5 +148,83 @@ func TestBodyGenerator(t *testing.T) {
 			require.NoError(g, err)
 		})
 
+		g.It("should document every accepted content type", func() {
+			req := bodyTestMultipleDecodersRequest{}
+			err := b.generateBodyDoc(ctx, b.swagger, &op, &req, reflect.TypeOf(req), shared.NewChipiCallbacks(nil))
+			require.NoError(g, err)
+
+			content := op.RequestBody.Value.Content
+			require.Len(g, content, 2)
+			assert.Equal(g, content["application/json"].Schema, content["application/x-www-form-urlencoded"].Schema)
+		})
+
+		g.It("should return an error if a content type has no decoder", func() {
+			req := bodyTestMultipleDecodersRequest{invalid: true}
+			err := b.generateBodyDoc(ctx, b.swagger, &op, &req, reflect.TypeOf(req), shared.NewChipiCallbacks(nil))
+			require.Error(g, err)
+			assert.Contains(g, err.Error(), "decoder for application/msgpack must implement BodyDecoder")
+		})
+
+		g.It("should document the body limits", func() {
+			req := bodyTestLimitedRequest{
+				JsonBodyDecoder: request.JsonBodyDecoder{DisallowUnknownFields: true},
+			}
+			err := b.generateBodyDoc(ctx, b.swagger, &op, &req, reflect.TypeOf(req), shared.NewChipiCallbacks(nil))
+			require.NoError(g, err)
+
+			assert.Equal(g, map[string]interface{}{"x-max-size": int64(1024)}, op.RequestBody.Value.Extensions)
+			assert.Equal(g, map[string]interface{}{
+				"x-disallow-unknown-fields": true,
+				"x-max-depth":               request.DefaultMaxDepth,
+			}, op.RequestBody.Value.Content.Get("application/json").Extensions)
+		})
+
+		g.It("should use the builder body limit", func() {
+			b.SetMaxBodySize(2048)
+
+			req := bodyTestWithDecoderRequest{}
+			err := b.generateBodyDoc(ctx, b.swagger, &op, &req, reflect.TypeOf(req), shared.NewChipiCallbacks(nil))
+			require.NoError(g, err)
+
+			assert.Equal(g, map[string]interface{}{"x-max-size": int64(2048)}, op.RequestBody.Value.Extensions)
+		})
+
+		g.It("should reject invalid body limits", func() {
+			err := b.Post(chi.NewRouter(), "/pet", &bodyTestInvalidLimitRequest{})
+			require.Error(g, err)
+			assert.Contains(g, err.Error(), `invalid size: "big"`)
+		})
+
+		g.It("should document multipart files", func() {
+			req := bodyTestMultipartRequest{}
+			err := b.generateBodyDoc(ctx, b.swagger, &op, &req, reflect.TypeOf(req), shared.NewChipiCallbacks(nil))
+			require.NoError(g, err)
+
+			mediaType := op.RequestBody.Value.Content.Get("multipart/form-data")
+			require.NotNil(g, mediaType)
+
+			data, err := json.Marshal(mediaType)
+			require.NoError(g, err)
+
+			assert.JSONEq(g, `{
+				"schema": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string"},
+						"avatar": {"type": "string", "format": "binary"},
+						"photos": {"type": "array", "items": {"type": "string", "format": "binary"}},
+						"License": {"type": "string", "format": "binary"},
+						"cover_image": {"type": "string", "format": "binary"}
+					}
+				},
+				"encoding": {
+					"avatar": {"contentType": "image/png"},
+					"photos": {"contentType": "application/octet-stream"},
+					"License": {"contentType": "application/octet-stream"},
+					"cover_image": {"contentType": "application/octet-stream"}
+				}
+			}`, string(data))
+		})
+
 	})
 }
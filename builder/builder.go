@@ -2,8 +2,11 @@ package builder
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 	"reflect"
+	"sync"
+	"sync/atomic"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
@@ -17,6 +20,13 @@ type rawHandler interface {
 	Handle(http.ResponseWriter, *http.Request)
 }
 
+// operation is the handler registered for a request object, it is
+// found by RouteFor when walking the router
+type operation struct {
+	http.Handler
+	reqObject interface{}
+}
+
 type Method struct {
 	pattern   string
 	method    string
@@ -27,6 +37,12 @@ type Builder struct {
 	schema  *schema.Schema
 	router  *chi.Mux
 	methods []*Method
+	config  *wrapper.Config
+
+	// responses validation, see ValidateResponses
+	contractHandler atomic.Pointer[ContractViolationHandler]
+	contractLock    sync.Mutex
+	contractSpec    *openapi3.T
 }
 
 func New(r *chi.Mux, infos *openapi3.Info) (*Builder, error) {
@@ -44,11 +60,80 @@ func New(r *chi.Mux, infos *openapi3.Info) (*Builder, error) {
 		swagger: swagger,
 		schema:  s,
 		router:  r,
+		config:  wrapper.NewConfig(),
 	}
 
 	return ret, nil
 }
 
+// SetNamingStrategy changes how components are named, it must be called
+// before the first document is generated
+func (b *Builder) SetNamingStrategy(naming schema.NamingStrategy) {
+	b.schema.SetNamingStrategy(naming)
+}
+
+// SetCollisionPolicy decides what to do when two types get the same component name
+func (b *Builder) SetCollisionPolicy(policy schema.CollisionPolicy) {
+	b.schema.SetCollisionPolicy(policy)
+}
+
+// SetEnumResolver defines the enums used to validate incoming requests and by
+// ServeSchema, it should be the same as the one given to GenerateSwagger (types
+// implementing shared.EnumInterface are always validated). It must be set before
+// registering the operations, the enum types must be comparable.
+func (b *Builder) SetEnumResolver(resolver shared.EnumResolverInterface) {
+	b.config.Callbacks = shared.NewChipiCallbacks(resolver)
+}
+
+// SetMaxBodySize limits the size of the request bodies of every operation, the max-size
+// tag of the Body field has precedence (ex: `max-size:"10MB"`), 0 means no limit.
+func (b *Builder) SetMaxBodySize(size int64) {
+	b.config.MaxBodySize = size
+}
+
+// SetDefaultErrorHandler is used for the operations without error handler, it
+// also receives the panics (as *wrapper.PanicError). The defaults must be set
+// before registering the operations.
+func (b *Builder) SetDefaultErrorHandler(handler wrapper.ErrorHandlerInterface) {
+	b.config.ErrorHandler = handler
+}
+
+// SetDefaultResponseEncoder is used for the operations with a Response field but no encoder
+func (b *Builder) SetDefaultResponseEncoder(encoder wrapper.ResponseEncoder) {
+	b.config.ResponseEncoder = encoder
+}
+
+// SetDefaultBodyDecoder is used for the operations with a Body field but no decoder,
+// it must be a wrapper.BodyDecoder or a wrapper.BodyDecoderWithRequest
+func (b *Builder) SetDefaultBodyDecoder(decoder interface{}) error {
+	if !wrapper.IsBodyDecoder(decoder) {
+		return errors.Errorf("%T must implement BodyDecoder", decoder)
+	}
+
+	b.config.BodyDecoder = decoder
+	return nil
+}
+
+// SetLogger enables the logging of the decoding failures, errors and panics, the
+// logger is also available to the handlers with wrapper.LoggerFromContext.
+func (b *Builder) SetLogger(logger *slog.Logger) {
+	b.config.Logger = logger
+}
+
+// Use adds interceptors running around the Handle method of every operation,
+// including the ones already registered, in the order they were added.
+// The interceptors are read without synchronization by the requests, Use must
+// be called before the router starts serving.
+func (b *Builder) Use(interceptors ...wrapper.Interceptor) {
+	b.config.Interceptors = append(b.config.Interceptors, interceptors...)
+}
+
+// SetRepanic makes the operations panic again once the panic is recorded on
+// the span instead of calling the error handler, useful in development.
+func (b *Builder) SetRepanic(enabled bool) {
+	b.config.Repanic = enabled
+}
+
 func (b *Builder) AddTag(tag *openapi3.Tag) {
 	b.swagger.Tags = append(b.swagger.Tags, tag)
 }
@@ -74,8 +159,14 @@ func (b *Builder) AddSecurityRequirement(req openapi3.SecurityRequirement) {
 	b.swagger.Security.With(req)
 }
 
+// SchemaJson returns the specification served by ServeSchema, generated with
+// the enum resolver given to SetEnumResolver
+func (b *Builder) SchemaJson(ctx context.Context) ([]byte, error) {
+	return b.GenerateJson(ctx, b.config.Callbacks)
+}
+
 func (b *Builder) ServeSchema(w http.ResponseWriter, r *http.Request) {
-	data, err := b.GenerateJson(r.Context(), shared.NewChipiCallbacks(nil))
+	data, err := b.SchemaJson(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -90,24 +181,24 @@ func (b *Builder) ServeSchema(w http.ResponseWriter, r *http.Request) {
 
 type CallbackFunc func(http.ResponseWriter, interface{})
 
-func (b *Builder) Get(r chi.Router, pattern string, reqObject interface{}) error {
-	return b.Method(r, pattern, "GET", reqObject)
+func (b *Builder) Get(r chi.Router, pattern string, reqObject interface{}, interceptors ...wrapper.Interceptor) error {
+	return b.Method(r, pattern, "GET", reqObject, interceptors...)
 }
 
-func (b *Builder) Post(r chi.Router, pattern string, reqObject interface{}) error {
-	return b.Method(r, pattern, "POST", reqObject)
+func (b *Builder) Post(r chi.Router, pattern string, reqObject interface{}, interceptors ...wrapper.Interceptor) error {
+	return b.Method(r, pattern, "POST", reqObject, interceptors...)
 }
 
-func (b *Builder) Patch(r chi.Router, pattern string, reqObject interface{}) error {
-	return b.Method(r, pattern, "PATCH", reqObject)
+func (b *Builder) Patch(r chi.Router, pattern string, reqObject interface{}, interceptors ...wrapper.Interceptor) error {
+	return b.Method(r, pattern, "PATCH", reqObject, interceptors...)
 }
 
-func (b *Builder) Put(r chi.Router, pattern string, reqObject interface{}) error {
-	return b.Method(r, pattern, "PUT", reqObject)
+func (b *Builder) Put(r chi.Router, pattern string, reqObject interface{}, interceptors ...wrapper.Interceptor) error {
+	return b.Method(r, pattern, "PUT", reqObject, interceptors...)
 }
 
-func (b *Builder) Delete(r chi.Router, pattern string, reqObject interface{}) error {
-	return b.Method(r, pattern, "DELETE", reqObject)
+func (b *Builder) Delete(r chi.Router, pattern string, reqObject interface{}, interceptors ...wrapper.Interceptor) error {
+	return b.Method(r, pattern, "DELETE", reqObject, interceptors...)
 }
 
 func (b *Builder) findRoute(typ reflect.Type, method string) (*chi.Context, error) {
@@ -129,7 +220,9 @@ func (b *Builder) findRoute(typ reflect.Type, method string) (*chi.Context, erro
 	return nil, errors.New("route not found : " + method + " - " + routeExample)
 }
 
-func (b *Builder) Method(r chi.Router, pattern string, method string, reqObject interface{}) error {
+// Method registers an operation, the interceptors only run for this operation
+// after the ones given to Use.
+func (b *Builder) Method(r chi.Router, pattern string, method string, reqObject interface{}, interceptors ...wrapper.Interceptor) error {
 
 	typ := reflect.TypeOf(reqObject)
 	if (typ.Kind() != reflect.Ptr) || (typ.Elem().Kind() != reflect.Struct) {
@@ -137,9 +230,22 @@ func (b *Builder) Method(r chi.Router, pattern string, method string, reqObject
 	}
 
 	if _, ok := reqObject.(wrapper.HandlerInterface); ok {
-		r.Method(method, pattern, wrapper.WrapRequest(reqObject))
+		err := b.checkRequestObject(reqObject)
+		if err != nil {
+			return err
+		}
+
+		r.Method(method, pattern, &operation{
+			Handler:   b.checkContract(reqObject, wrapper.WrapRequestWithConfig(reqObject, b.config, interceptors...)),
+			reqObject: reqObject,
+		})
+	} else if len(interceptors) > 0 {
+		return errors.Errorf("%T: interceptors require HandlerInterface", reqObject)
 	} else if rr, ok := reqObject.(rawHandler); ok {
-		r.Method(method, pattern, http.HandlerFunc(rr.Handle))
+		r.Method(method, pattern, &operation{
+			Handler:   b.checkContract(reqObject, http.HandlerFunc(rr.Handle)),
+			reqObject: reqObject,
+		})
 	} else {
 		return errors.Errorf("%T object must implement HandlerInterface interface", reqObject)
 	}
@@ -149,17 +255,112 @@ func (b *Builder) Method(r chi.Router, pattern string, method string, reqObject
 		method:    method,
 		reqObject: reqObject,
 	})
+	b.resetContract()
 
 	return nil
 }
 
+// Router returns the router given to New
+func (b *Builder) Router() *chi.Mux {
+	return b.router
+}
+
+// RouteFor returns the method and the full pattern (including the mounted routers) of
+// the operation registered with a request object of the same type as reqObject
+func (b *Builder) RouteFor(reqObject interface{}) (method string, pattern string, err error) {
+	typ := reflect.TypeOf(reqObject)
+	errFound := errors.New("found")
+
+	err = chi.Walk(b.router, func(m string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if op, ok := handler.(*operation); ok && (reflect.TypeOf(op.reqObject) == typ) {
+			method = m
+			pattern = route
+			return errFound
+		}
+
+		return nil
+	})
+
+	if err == errFound {
+		return method, pattern, nil
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return "", "", errors.Errorf("%T is not registered on the router", reqObject)
+}
+
+// checkRequestObject reports what is missing to handle the requests, rather
+// than failing on the first request
+func (b *Builder) checkRequestObject(reqObject interface{}) error {
+	typ := reflect.TypeOf(reqObject).Elem()
+
+	if _, found := typ.FieldByName("Body"); found {
+		if _, err := wrapper.MaxBodySize(reqObject, 0); err != nil {
+			return errors.Wrapf(err, "%T Body", reqObject)
+		}
+
+		_, hasDecoders := reqObject.(wrapper.BodyDecodersInterface)
+		if !hasDecoders && !wrapper.IsBodyDecoder(reqObject) && (b.config.BodyDecoder == nil) {
+			return errors.Errorf("%T must implement BodyDecoder (or a default one must be set)", reqObject)
+		}
+	}
+
+	if f, found := typ.FieldByName("Response"); found {
+		responseType := f.Type
+		if responseType.Kind() == reflect.Ptr {
+			responseType = responseType.Elem()
+		}
+
+		if _, _, err := streamItemType(responseType); err != nil {
+			return errors.Wrapf(err, "%T", reqObject)
+		}
+
+		_, hasEncoders := reqObject.(wrapper.ResponseEncodersInterface)
+		_, hasEncoder := reqObject.(wrapper.ResponseEncoder)
+		if !hasEncoders && !hasEncoder && (b.config.ResponseEncoder == nil) {
+			return errors.Errorf("%T must implement ResponseEncoder (or a default one must be set)", reqObject)
+		}
+	}
+
+	if _, ok := reqObject.(wrapper.ErrorHandlerInterface); !ok && (b.config.ErrorHandler == nil) {
+		return errors.Errorf("%T must implement ErrorHandlerInterface (or a default one must be set)", reqObject)
+	}
+
+	if err := wrapper.CheckEnumTypes(b.config.Callbacks, reqObject); err != nil {
+		return errors.Wrapf(err, "%T", reqObject)
+	}
+
+	return nil
+}
+
+// ClearCache is kept for compatibility, every document is now generated
+// with its own components.
 func (b *Builder) ClearCache() {
-	b.swagger.Components.Schemas = make(openapi3.Schemas)
+	if b.swagger.Components != nil {
+		b.swagger.Components.Schemas = make(openapi3.Schemas)
+	}
 }
 
+// GenerateSwagger generates the document of the registered operations, the same
+// operations always produce the same document: the components only depend on
+// callbacksObject and the parameters follow the declaration order (path
+// parameters in the pattern order, then the Query and Header fields).
 func (b *Builder) GenerateSwagger(ctx context.Context, callbacksObject shared.ChipiCallbacks) (*openapi3.T, error) {
 
 	swagger := *b.swagger
+
+	// the schemas depend on the callbacks, they must not leak to the next documents
+	components := openapi3.Components{}
+	if b.swagger.Components != nil {
+		components = *b.swagger.Components
+	}
+	components.Schemas = make(openapi3.Schemas)
+	swagger.Components = &components
+	swagger.Paths = openapi3.NewPaths()
+
 	for _, m := range b.methods {
 
 		typ := reflect.TypeOf(m.reqObject).Elem()
@@ -179,7 +380,7 @@ func (b *Builder) GenerateSwagger(ctx context.Context, callbacksObject shared.Ch
 		}
 
 		op := openapi3.NewOperation()
-		op.OperationID = typ.Name()
+		op.OperationID = wrapper.OperationID(m.reqObject)
 
 		err = generateOperationDoc(op, typ)
 		if err != nil {
@@ -3,13 +3,19 @@ package builder
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/franela/goblin"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/request"
 	"github.com/schmurfy/chipi/response"
 	"github.com/schmurfy/chipi/shared"
+	"github.com/schmurfy/chipi/wrapper"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,6 +31,91 @@ func (r *builderTestPathRequest) Handle(ctx context.Context, w http.ResponseWrit
 	return nil
 }
 
+type builderTestIncompleteRequest struct {
+	Path struct {
+	} `example:"/pets"`
+
+	Body struct {
+		Name string
+	}
+
+	Response struct {
+		Id int
+	}
+}
+
+func (r *builderTestIncompleteRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+type builderTestKind string
+
+type builderTestPet struct {
+	Name string          `json:"name"`
+	Kind builderTestKind `json:"kind"`
+}
+
+type builderTestTags []string
+
+func (builderTestTags) CHIPI_Enum() shared.Enum {
+	return shared.Enum{{Title: "None", Value: builderTestTags{}}}
+}
+
+type builderTestUncomparableEnumRequest struct {
+	Path  struct{}
+	Query struct {
+		Tags builderTestTags
+	}
+}
+
+func (r *builderTestUncomparableEnumRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+type builderTestResolver struct{}
+
+func (r *builderTestResolver) EnumResolver(t reflect.Type) (bool, shared.Enum) {
+	if t == reflect.TypeOf(builderTestKind("")) {
+		return true, shared.Enum{{Title: "Dog", Value: "dog"}}
+	}
+	return false, nil
+}
+
+type builderTestOrderRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+	request.JsonBodyDecoder
+
+	Path struct {
+		Owner string
+		Id    int
+	} `example:"/owners/john/pets/43"`
+
+	Query struct {
+		Zeta  string
+		Alpha string
+	}
+
+	Header struct {
+		B string
+		A string
+	}
+
+	Body builderTestPet
+
+	Response builderTestPet
+}
+
+func (r *builderTestOrderRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+type builderTestExtraComponents struct{}
+
+func (c *builderTestExtraComponents) ExtraComponentsAndPaths() (openapi3.Schemas, openapi3.Paths) {
+	return openapi3.Schemas{"Extra": openapi3.NewStringSchema().NewRef()}, openapi3.Paths{}
+}
+
 func convertToSwagger(g *goblin.G, data []byte) *openapi3.T {
 	swagger := &openapi3.T{
 		OpenAPI: "3.1.0",
@@ -97,6 +188,23 @@ func TestBuilder(t *testing.T) {
 
 			})
 
+			g.It("should find the route of a request object", func() {
+				petsRoute := chi.NewRouter()
+				router.Mount("/pets", petsRoute)
+				petsRoute.Group(func(r chi.Router) {
+					err := b.Put(r, "/{Id}", &builderTestPathRequest{})
+					require.NoError(g, err)
+				})
+
+				method, pattern, err := b.RouteFor(&builderTestPathRequest{})
+				require.NoError(g, err)
+				assert.Equal(g, "PUT", method)
+				assert.Equal(g, "/pets/{Id}", pattern)
+
+				_, _, err = b.RouteFor(&builderTestIncompleteRequest{})
+				require.Error(g, err)
+			})
+
 			g.Describe("test filter routes", func() {
 
 				routePath := "/pets/{Id}"
@@ -143,5 +251,171 @@ func TestBuilder(t *testing.T) {
 
 		})
 
+		g.Describe("generated documents", func() {
+			var b *Builder
+			var ctx context.Context
+
+			g.BeforeEach(func() {
+				var err error
+				router := chi.NewRouter()
+				ctx = context.Background()
+
+				b, err = New(router, &openapi3.Info{})
+				require.NoError(g, err)
+
+				// the components are created before the first document
+				b.AddSecurityScheme("key", &openapi3.SecurityScheme{Type: "apiKey", In: "header", Name: "X-Key"})
+
+				err = b.Post(router, "/owners/{Owner}/pets/{Id}", &builderTestOrderRequest{})
+				require.NoError(g, err)
+			})
+
+			g.It("should list the parameters in declaration order", func() {
+				swagger, err := b.GenerateSwagger(ctx, shared.NewChipiCallbacks(nil))
+				require.NoError(g, err)
+
+				op := swagger.Paths.Value("/owners/{Owner}/pets/{Id}").Post
+				require.NotNil(g, op)
+
+				names := []string{}
+				for _, param := range op.Parameters {
+					names = append(names, param.Value.In+"."+param.Value.Name)
+				}
+
+				assert.Equal(g, []string{"path.Owner", "path.Id", "query.zeta", "query.alpha", "header.B", "header.A"}, names)
+			})
+
+			g.It("should not share the components between documents", func() {
+				expected, err := b.GenerateJson(ctx, shared.NewChipiCallbacks(nil))
+				require.NoError(g, err)
+
+				extra, err := b.GenerateSwagger(ctx, shared.NewChipiCallbacks(&builderTestExtraComponents{}))
+				require.NoError(g, err)
+				require.NotNil(g, extra.Components.Schemas["Extra"])
+
+				json, err := b.GenerateJson(ctx, shared.NewChipiCallbacks(nil))
+				require.NoError(g, err)
+				assert.Equal(g, string(expected), string(json))
+			})
+
+			g.It("should serve the schema with the enum resolver", func() {
+				b.SetEnumResolver(&builderTestResolver{})
+
+				w := httptest.NewRecorder()
+				b.ServeSchema(w, httptest.NewRequest("GET", "/swagger.json", nil))
+				require.Equal(g, http.StatusOK, w.Code)
+
+				assert.Contains(g, w.Body.String(), `"enum":["dog"]`)
+			})
+		})
+
+		g.Describe("interceptors", func() {
+			g.It("should run the builder interceptors before the operation ones", func() {
+				router := chi.NewRouter()
+				b, err := New(router, &openapi3.Info{})
+				require.NoError(g, err)
+
+				steps := []string{}
+				step := func(name string) wrapper.Interceptor {
+					return func(ctx context.Context, call *wrapper.Call, next wrapper.HandleFunc) error {
+						steps = append(steps, name)
+						return next(ctx, call)
+					}
+				}
+
+				err = b.Get(router, "/pets/{Id}", &builderTestPathRequest{}, step("operation"))
+				require.NoError(g, err)
+
+				// also applies to the operations already registered
+				b.Use(step("builder"))
+
+				r := httptest.NewRequest("GET", "/pets/42", nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.Equal(g, []string{"builder", "operation"}, steps)
+			})
+		})
+
+		g.Describe("defaults", func() {
+			var b *Builder
+			var router *chi.Mux
+
+			g.BeforeEach(func() {
+				var err error
+				router = chi.NewRouter()
+
+				b, err = New(router, &openapi3.Info{})
+				require.NoError(g, err)
+			})
+
+			g.It("should report missing decoder, encoder and error handler", func() {
+				err := b.Post(router, "/pets", &builderTestIncompleteRequest{})
+				require.Error(g, err)
+				assert.Contains(g, err.Error(), "must implement BodyDecoder")
+
+				err = b.SetDefaultBodyDecoder(&request.JsonBodyDecoder{})
+				require.NoError(g, err)
+
+				err = b.Post(router, "/pets", &builderTestIncompleteRequest{})
+				require.Error(g, err)
+				assert.Contains(g, err.Error(), "must implement ResponseEncoder")
+
+				b.SetDefaultResponseEncoder(&response.JsonEncoder{})
+
+				err = b.Post(router, "/pets", &builderTestIncompleteRequest{})
+				require.Error(g, err)
+				assert.Contains(g, err.Error(), "must implement ErrorHandlerInterface")
+
+				b.SetDefaultErrorHandler(&response.ErrorEncoder{})
+
+				err = b.Post(router, "/pets", &builderTestIncompleteRequest{})
+				require.NoError(g, err)
+			})
+
+			g.It("should reject the uncomparable enum types", func() {
+				b.SetDefaultErrorHandler(&response.ErrorEncoder{})
+
+				err := b.Get(router, "/pets", &builderTestUncomparableEnumRequest{})
+				require.Error(g, err)
+				assert.Contains(g, err.Error(), "is not comparable")
+			})
+
+			g.It("should reject invalid default decoders", func() {
+				err := b.SetDefaultBodyDecoder(&response.JsonEncoder{})
+				require.Error(g, err)
+			})
+
+			g.It("should use the defaults", func() {
+				require.NoError(g, b.SetDefaultBodyDecoder(&request.JsonBodyDecoder{}))
+				b.SetDefaultResponseEncoder(&response.JsonEncoder{})
+				b.SetDefaultErrorHandler(&response.ErrorEncoder{})
+
+				err := b.Post(router, "/pets", &builderTestIncompleteRequest{})
+				require.NoError(g, err)
+
+				r := httptest.NewRequest("POST", "/pets", strings.NewReader(`{"Name": "rex"}`))
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"Id": 0}`, w.Body.String())
+
+				r = httptest.NewRequest("POST", "/pets", strings.NewReader(`{"Name": `))
+				w = httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+
+				require.Equal(g, http.StatusBadRequest, w.Code)
+
+				doc, err := b.GenerateSwagger(context.Background(), shared.NewChipiCallbacks(nil))
+				require.NoError(g, err)
+
+				op := doc.Paths.Find("/pets").Post
+				require.NotNil(g, op.RequestBody.Value.Content.Get("application/json"))
+				require.NotNil(g, op.Responses.Status(200).Value.Content.Get("application/json"))
+			})
+		})
+
 	})
 }
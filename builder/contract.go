@@ -0,0 +1,198 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/shared"
+	"github.com/schmurfy/chipi/wrapper"
+)
+
+// the captured bodies larger than this are not validated
+const _maxContractBody = 1 << 20
+
+// ContractViolation is a response not matching the specification of its operation
+type ContractViolation struct {
+	OperationID string
+	Method      string
+	Route       string
+	Status      int
+	Err         error
+}
+
+func (v *ContractViolation) Error() string {
+	return fmt.Sprintf("%s (%s %s): response %d does not match the specification: %s",
+		v.OperationID, v.Method, v.Route, v.Status, v.Err.Error())
+}
+
+func (v *ContractViolation) Unwrap() error {
+	return v.Err
+}
+
+// ContractViolationHandler receives the responses not matching the specification
+type ContractViolationHandler func(ctx context.Context, violation *ContractViolation)
+
+// ValidateResponses checks the responses of every operation against the generated
+// specification: the status code, the content type and the json bodies. The errors
+// (4xx and 5xx) are not documented and not checked. The responses are buffered so
+// it is meant for tests or staging, nil disables the validation.
+func (b *Builder) ValidateResponses(handler ContractViolationHandler) {
+	if handler == nil {
+		b.contractHandler.Store(nil)
+	} else {
+		b.contractHandler.Store(&handler)
+	}
+
+	b.resetContract()
+}
+
+// contractSpecification returns the specification used to validate the responses
+func (b *Builder) contractSpecification(ctx context.Context) (*openapi3.T, error) {
+	b.contractLock.Lock()
+	defer b.contractLock.Unlock()
+
+	if b.contractSpec != nil {
+		return b.contractSpec, nil
+	}
+
+	spec, err := b.resolvedSpecification(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b.contractSpec = spec
+	return spec, nil
+}
+
+// resetContract forgets the specification when an operation is added
+func (b *Builder) resetContract() {
+	b.contractLock.Lock()
+	defer b.contractLock.Unlock()
+
+	b.contractSpec = nil
+}
+
+// checkContract wraps the handler of an operation to validate its responses
+func (b *Builder) checkContract(reqObject interface{}, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := b.contractHandler.Load()
+		if handler == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := wrapper.NewResponseRecorder(w)
+		cw.CaptureBody(_maxContractBody)
+		next.ServeHTTP(cw, r)
+
+		status := cw.Status()
+		// the errors are not documented
+		if status >= http.StatusBadRequest {
+			return
+		}
+
+		var route string
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+
+		err := b.validateResponse(r, route, cw)
+		if err != nil {
+			(*handler)(r.Context(), &ContractViolation{
+				OperationID: wrapper.OperationID(reqObject),
+				Method:      r.Method,
+				Route:       route,
+				Status:      status,
+				Err:         err,
+			})
+		}
+	})
+}
+
+func (b *Builder) validateResponse(r *http.Request, route string, cw *wrapper.ResponseRecorder) error {
+	ctx := r.Context()
+
+	spec, err := b.contractSpecification(ctx)
+	if err != nil {
+		return fmt.Errorf("generating the specification: %w", err)
+	}
+
+	pathItem := spec.Paths.Find(route)
+	if pathItem == nil {
+		return fmt.Errorf("route %s is not documented", route)
+	}
+
+	op := pathItem.GetOperation(r.Method)
+	if op == nil {
+		return fmt.Errorf("method %s is not documented", r.Method)
+	}
+
+	// net/http sends a 200 when nothing is written
+	if !cw.Started() && (op.Responses.Status(http.StatusNoContent) != nil) {
+		return nil
+	}
+
+	status := cw.Status()
+	responseRef := op.Responses.Status(status)
+	if (responseRef == nil) || (responseRef.Value == nil) {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+
+	contentType := cw.Header().Get("Content-Type")
+	content := responseRef.Value.Content
+	body, complete := cw.Body()
+
+	if len(content) == 0 {
+		switch {
+		case !complete:
+			return fmt.Errorf("no body is documented, got more than %d bytes (%s)", _maxContractBody, contentType)
+		case len(body) > 0:
+			return fmt.Errorf("no body is documented, got %d bytes (%s)", len(body), contentType)
+		}
+		return nil
+	}
+
+	if content.Get(contentType) == nil {
+		return fmt.Errorf("content type %q is not documented, expected one of: %s",
+			contentType, strings.Join(shared.SortedContentTypes(content), ", "))
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request: r,
+			Route: &routers.Route{
+				Spec:      spec,
+				Path:      route,
+				PathItem:  pathItem,
+				Method:    r.Method,
+				Operation: op,
+			},
+		},
+		Status: status,
+		Header: cw.Header(),
+		Options: &openapi3filter.Options{
+			// only the json bodies are validated
+			ExcludeResponseBody: !complete || !isJsonContentType(contentType),
+			MultiError:          true,
+		},
+	}
+	input.SetBodyBytes(body)
+
+	return openapi3filter.ValidateResponse(ctx, input)
+}
+
+func isJsonContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return (mediaType == "application/json") || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,134 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contractPet struct {
+	Id   int    `json:"id"`
+	Name string `json:"name" chipi:"required"`
+}
+
+type contractRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+
+	Path struct {
+		Mode string
+	} `example:"/pets/valid"`
+
+	Response contractPet
+}
+
+func (r *contractRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	switch r.Path.Mode {
+	case "valid":
+		r.Response = contractPet{Id: 1, Name: "rex"}
+
+	// bypass the Response field
+	case "wrong-body":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "one"}`)
+
+	case "wrong-status":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": 1, "name": "rex"}`)
+
+	case "wrong-content-type":
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "rex")
+
+	case "error":
+		return errors.New("failed")
+	}
+
+	return nil
+}
+
+type contractNoContentRequest struct {
+	response.ErrorEncoder
+
+	Path struct{} `example:"/pets"`
+}
+
+func (r *contractNoContentRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+func TestContract(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("ValidateResponses", func() {
+		var router *chi.Mux
+		var violations []*ContractViolation
+
+		g.BeforeEach(func() {
+			router = chi.NewRouter()
+			violations = nil
+
+			b, err := New(router, &openapi3.Info{})
+			require.NoError(g, err)
+
+			require.NoError(g, b.Get(router, "/pets/{Mode}", &contractRequest{}))
+			require.NoError(g, b.Delete(router, "/pets", &contractNoContentRequest{}))
+
+			b.ValidateResponses(func(ctx context.Context, violation *ContractViolation) {
+				violations = append(violations, violation)
+			})
+		})
+
+		call := func(method string, path string) int {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			return w.Code
+		}
+
+		g.It("should accept valid responses", func() {
+			assert.Equal(g, http.StatusOK, call("GET", "/pets/valid"))
+			assert.Equal(g, http.StatusOK, call("DELETE", "/pets"))
+			assert.Empty(g, violations)
+		})
+
+		g.It("should ignore errors", func() {
+			assert.Equal(g, http.StatusBadRequest, call("GET", "/pets/error"))
+			assert.Empty(g, violations)
+		})
+
+		g.It("should report invalid bodies", func() {
+			assert.Equal(g, http.StatusOK, call("GET", "/pets/wrong-body"))
+			require.Len(g, violations, 1)
+
+			violation := violations[0]
+			assert.Equal(g, "contractRequest", violation.OperationID)
+			assert.Equal(g, "GET", violation.Method)
+			assert.Equal(g, "/pets/{Mode}", violation.Route)
+			assert.Equal(g, http.StatusOK, violation.Status)
+			assert.Contains(g, violation.Error(), "name")
+		})
+
+		g.It("should report undocumented statuses", func() {
+			assert.Equal(g, http.StatusCreated, call("GET", "/pets/wrong-status"))
+			require.Len(g, violations, 1)
+			assert.Contains(g, violations[0].Error(), "status 201 is not documented")
+		})
+
+		g.It("should report undocumented content types", func() {
+			call("GET", "/pets/wrong-content-type")
+			require.Len(g, violations, 1)
+			assert.Contains(g, violations[0].Error(), `content type "text/plain" is not documented`)
+		})
+	})
+}
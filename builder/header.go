@@ -22,7 +22,7 @@ func (b *Builder) generateHeadersDoc(ctx context.Context, swagger *openapi3.T, o
 	for i := 0; i < headerStructType.NumField(); i++ {
 		field := headerStructType.Field(i)
 
-		schema, err := b.schema.GenerateSchemaFor(ctx, swagger, field.Type)
+		schema, err := b.schema.GenerateParameterSchemaFor(ctx, swagger, field.Type)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,33 @@
+package builder
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/schmurfy/chipi/mock"
+)
+
+// MockHandler returns a handler serving every registered operation without
+// calling them: the parameters and bodies are validated and the responses are
+// built from the example tags and the schemas, see the mock package.
+// The operations registered after this call are not served.
+func (b *Builder) MockHandler() (http.Handler, error) {
+	spec, err := b.resolvedSpecification(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return mock.NewHandler(spec)
+}
+
+// resolvedSpecification returns the served specification loaded
+// from its json to resolve the references
+func (b *Builder) resolvedSpecification(ctx context.Context) (*openapi3.T, error) {
+	data, err := b.SchemaJson(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return openapi3.NewLoader().LoadFromData(data)
+}
@@ -0,0 +1,72 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPet struct {
+	Id   int    `json:"id" example:"42"`
+	Name string `json:"name" example:"rex"`
+}
+
+type mockGetPetRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+
+	Path struct {
+		Id int
+	} `example:"/pets/1"`
+
+	Response mockPet
+}
+
+func (r *mockGetPetRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return errors.New("should not be called")
+}
+
+func TestMockHandler(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("MockHandler", func() {
+		var handler http.Handler
+
+		g.Before(func() {
+			router := chi.NewRouter()
+
+			b, err := New(router, &openapi3.Info{})
+			require.NoError(g, err)
+
+			require.NoError(g, b.Get(router, "/pets/{Id}", &mockGetPetRequest{}))
+
+			handler, err = b.MockHandler()
+			require.NoError(g, err)
+		})
+
+		g.It("should answer with the examples", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/pets/1", nil))
+
+			require.Equal(g, http.StatusOK, w.Code)
+			assert.JSONEq(g, `{"id": 42, "name": "rex"}`, w.Body.String())
+		})
+
+		g.It("should validate the parameters", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/pets/rex", nil))
+
+			require.Equal(g, http.StatusBadRequest, w.Code)
+			assert.Contains(g, w.Body.String(), "request.path.Id")
+		})
+	})
+}
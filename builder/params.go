@@ -29,7 +29,7 @@ func (b *Builder) generateParametersDoc(ctx context.Context, swagger *openapi3.T
 			return errors.Errorf("wrong path struct, field %s expected on %+v %s", key, pathField, requestObjectType.Name())
 		}
 
-		schema, err := b.schema.GenerateSchemaFor(ctx, swagger, paramField.Type)
+		schema, err := b.schema.GenerateParameterSchemaFor(ctx, swagger, paramField.Type)
 		if err != nil {
 			return err
 		}
@@ -26,7 +26,7 @@ func (b *Builder) generateQueryParametersDoc(ctx context.Context, swagger *opena
 			continue
 		}
 
-		fieldSchema, err := b.schema.GenerateSchemaFor(ctx, swagger, field.Type)
+		fieldSchema, err := b.schema.GenerateParameterSchemaFor(ctx, swagger, field.Type)
 		if err != nil {
 			return err
 		}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/schmurfy/chipi/response"
 	"github.com/schmurfy/chipi/schema"
 	"github.com/schmurfy/chipi/shared"
 	"github.com/schmurfy/chipi/wrapper"
@@ -18,8 +19,9 @@ func (b *Builder) generateResponseDoc(ctx context.Context, swagger *openapi3.T,
 	if found {
 		resp := openapi3.NewResponse()
 
-		// check that a body decoder is available
-		if _, ok := requestObject.(wrapper.ResponseEncoder); !ok {
+		// check that a response encoder is available
+		encoders, hasEncoders := requestObject.(wrapper.ResponseEncodersInterface)
+		if _, ok := requestObject.(wrapper.ResponseEncoder); !ok && !hasEncoders && (b.config.ResponseEncoder == nil) {
 			return fmt.Errorf("%s must implement ResponseEncoder", requestObjectType.Name())
 		}
 
@@ -28,6 +30,17 @@ func (b *Builder) generateResponseDoc(ctx context.Context, swagger *openapi3.T,
 			contentType = "application/json"
 		}
 
+		contentTypes := []string{contentType}
+		if hasEncoders {
+			list := encoders.ResponseEncoders()
+			if len(list) == 0 {
+				return fmt.Errorf("%s must return at least one ResponseEncoder", requestObjectType.Name())
+			}
+
+			// one entry for each produced content type
+			contentTypes = shared.SortedContentTypes(list)
+		}
+
 		typ := responseField.Type
 		if typ.Kind() == reflect.Ptr {
 			typ = typ.Elem()
@@ -38,30 +51,40 @@ func (b *Builder) generateResponseDoc(ctx context.Context, swagger *openapi3.T,
 			return err
 		}
 
-		if typ.Kind() == reflect.Struct {
-			responseSchema, err := b.schema.GenerateFilteredSchemaFor(ctx, swagger, typ, callbacksObject)
+		itemType, isStream, err := streamItemType(typ)
+		if err != nil {
+			return err
+		}
+
+		if isStream {
+			// documented as the list of the events data
+			if !hasContentType && !hasEncoders {
+				contentTypes = []string{"text/event-stream"}
+			}
+
+			responseSchema, err := b.schema.GenerateFilteredSchemaFor(ctx, swagger, reflect.SliceOf(itemType), callbacksObject)
 			if err != nil {
 				return err
 			}
 
-			resp.Content = openapi3.Content{
-				contentType: &openapi3.MediaType{
-					Schema: responseSchema,
-				},
-			}
-		} else if typ.Kind() == reflect.Slice {
+			resp.Content = responseContent(contentTypes, responseSchema)
+		} else if typ.Kind() == reflect.Struct {
 			responseSchema, err := b.schema.GenerateFilteredSchemaFor(ctx, swagger, typ, callbacksObject)
 			if err != nil {
 				return err
 			}
-			if responseSchema.Value.Format == "binary" {
-				contentType = "application/octet-stream"
+
+			resp.Content = responseContent(contentTypes, responseSchema)
+		} else if (typ.Kind() == reflect.Slice) || (typ.Kind() == reflect.Chan) || (typ.Kind() == reflect.Func) {
+			// iter.Seq[T] and channels are documented as arrays
+			responseSchema, err := b.schema.GenerateFilteredSchemaFor(ctx, swagger, typ, callbacksObject)
+			if err != nil {
+				return err
 			}
-			resp.Content = openapi3.Content{
-				contentType: &openapi3.MediaType{
-					Schema: responseSchema,
-				},
+			if !hasEncoders && (responseSchema.Value.Format == "binary") {
+				contentTypes = []string{"application/octet-stream"}
 			}
+			resp.Content = responseContent(contentTypes, responseSchema)
 		}
 
 		responses.Set("200", &openapi3.ResponseRef{
@@ -82,6 +105,35 @@ func (b *Builder) generateResponseDoc(ctx context.Context, swagger *openapi3.T,
 	return nil
 }
 
+var (
+	_streamInterfaceType = reflect.TypeOf((*response.StreamInterface)(nil)).Elem()
+)
+
+// the type of the values sent by a streamed response
+func streamItemType(typ reflect.Type) (reflect.Type, bool, error) {
+	if typ.Implements(_streamInterfaceType) {
+		// the zero value of an interface is nil, the item type is unknown
+		if typ.Kind() == reflect.Interface {
+			return nil, false, fmt.Errorf("Response: the stream type %v must be concrete", typ)
+		}
+
+		return reflect.Zero(typ).Interface().(response.StreamInterface).ItemType(), true, nil
+	}
+
+	return nil, false, nil
+}
+
+func responseContent(contentTypes []string, responseSchema *openapi3.SchemaRef) openapi3.Content {
+	ret := openapi3.Content{}
+	for _, contentType := range contentTypes {
+		ret[contentType] = &openapi3.MediaType{
+			Schema: responseSchema,
+		}
+	}
+
+	return ret
+}
+
 func fillResponseFromTags(requestObjectType reflect.Type, resp *openapi3.Response, f reflect.StructField) error {
 	nilValue := reflect.New(requestObjectType)
 
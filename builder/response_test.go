@@ -3,6 +3,8 @@ package builder
 import (
 	"context"
 	"encoding/json"
+	"iter"
+	"net/http"
 	"reflect"
 	"testing"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/schmurfy/chipi/response"
 	"github.com/schmurfy/chipi/shared"
+	"github.com/schmurfy/chipi/wrapper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -26,6 +29,30 @@ type Inline struct {
 	Field2 int `json:"field2"`
 }
 
+type interfaceStreamRequest struct {
+	response.SSEEncoder
+	response.ErrorEncoder
+
+	Path     struct{}
+	Response response.StreamInterface
+}
+
+func (r *interfaceStreamRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+type multiEncodersRequest struct {
+	Response []Parent
+}
+
+func (r *multiEncodersRequest) ResponseEncoders() map[string]wrapper.ResponseEncoder {
+	return map[string]wrapper.ResponseEncoder{
+		"application/json": &response.JsonEncoder{},
+		"application/xml":  &response.XmlEncoder{},
+		"text/csv":         &response.CsvEncoder{},
+	}
+}
+
 func TestResponse(t *testing.T) {
 	g := goblin.Goblin(t)
 
@@ -91,6 +118,95 @@ func TestResponse(t *testing.T) {
 
 		})
 
+		g.It("should document every produced content type", func() {
+			req := multiEncodersRequest{}
+
+			err := b.generateResponseDoc(ctx, b.swagger, op, &req, reflect.TypeOf(req), shared.NewChipiCallbacks(nil))
+			require.NoError(g, err)
+
+			resp, found := op.Responses.Map()["200"]
+			require.True(g, found)
+
+			require.Len(g, resp.Value.Content, 3)
+			for _, contentType := range []string{"application/json", "application/xml", "text/csv"} {
+				mediaType := resp.Value.Content.Get(contentType)
+				require.NotNil(g, mediaType, contentType)
+				assert.Equal(g, "array", mediaType.Schema.Value.Type.Slice()[0])
+			}
+		})
+
+		g.It("should document server-sent events", func() {
+			req := struct {
+				response.SSEEncoder
+				Response response.Stream[Inline]
+			}{}
+
+			err := b.generateResponseDoc(ctx, b.swagger, op, &req, reflect.TypeOf(req), shared.NewChipiCallbacks(nil))
+			require.NoError(g, err)
+
+			resp, found := op.Responses.Map()["200"]
+			require.True(g, found)
+
+			require.Len(g, resp.Value.Content, 1)
+			mediaType := resp.Value.Content.Get("text/event-stream")
+			require.NotNil(g, mediaType)
+
+			data, err := json.Marshal(mediaType.Schema)
+			require.NoError(g, err)
+
+			assert.JSONEq(g, `{
+				"type": "array",
+				"items": {"$ref": "#/components/schemas/builder.Inline"}
+			}`, string(data))
+		})
+
+		g.It("should reject the stream interfaces", func() {
+			req := struct {
+				response.SSEEncoder
+				Response response.StreamInterface
+			}{}
+
+			err := b.generateResponseDoc(ctx, b.swagger, op, &req, reflect.TypeOf(req), shared.NewChipiCallbacks(nil))
+			require.Error(g, err)
+			assert.Contains(g, err.Error(), "must be concrete")
+
+			router := chi.NewRouter()
+			err = b.Get(router, "/events", &interfaceStreamRequest{})
+			require.Error(g, err)
+			assert.Contains(g, err.Error(), "must be concrete")
+		})
+
+		g.It("should document sequences as arrays", func() {
+			seq := struct {
+				response.NDJSONEncoder
+				Response iter.Seq[Inline] `content-type:"application/x-ndjson"`
+			}{}
+			ch := struct {
+				response.JsonEncoder
+				Response <-chan Inline
+			}{}
+
+			for contentType, req := range map[string]interface{}{"application/x-ndjson": &seq, "application/json": &ch} {
+				op = openapi3.NewOperation()
+				err := b.generateResponseDoc(ctx, b.swagger, op, req, reflect.TypeOf(req).Elem(), shared.NewChipiCallbacks(nil))
+				require.NoError(g, err)
+
+				resp, found := op.Responses.Map()["200"]
+				require.True(g, found)
+
+				mediaType := resp.Value.Content.Get(contentType)
+				require.NotNil(g, mediaType)
+
+				data, err := json.Marshal(mediaType.Schema)
+				require.NoError(g, err)
+
+				assert.JSONEq(g, `{
+					"type": "array",
+					"items": {"$ref": "#/components/schemas/builder.Inline"}
+				}`, string(data))
+			}
+		})
+
 		g.It("should handle json response", func() {
 			req := struct {
 				response.JsonEncoder
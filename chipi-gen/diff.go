@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schmurfy/chipi/diff"
+)
+
+// runDiff compares two specifications, the exit code is 1 when a change is breaking:
+//
+//	chipi-gen diff old.json new.json
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: chipi-gen diff <old spec> <new spec>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	base, err := loadSpecification(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", flags.Arg(0), err)
+	}
+
+	revision, err := loadSpecification(flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", flags.Arg(1), err)
+	}
+
+	report := diff.Compare(base, revision)
+
+	err = report.Write(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if report.HasBreaking() {
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -3,21 +3,35 @@ package main
 import (
 	"flag"
 	"fmt"
-	"go/parser"
-	"go/token"
 	"os"
 
-	"github.com/dave/dst"
-	"github.com/dave/dst/decorator"
 	"github.com/schmurfy/chipi/gen"
 )
 
 func main() {
+	commands := map[string]func([]string) error{
+		"mock": runMock,
+		"diff": runDiff,
+	}
+
+	if len(os.Args) > 1 {
+		if command, found := commands[os.Args[1]]; found {
+			err := command(os.Args[2:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			return
+		}
+	}
+
 	folder := ""
 	noCreate := false
+	check := false
 
 	flag.StringVar(&folder, "dir", "", "which folder to generate data for")
 	flag.BoolVar(&noCreate, "dry", false, "only shows which files would be created")
+	flag.BoolVar(&check, "check", false, "only checks that the generated files are up to date, exits with 1 if not")
 	flag.Parse()
 
 	if folder == "" {
@@ -25,48 +39,26 @@ func main() {
 		os.Exit(1)
 	}
 
-	err := gen.InspectDir(folder, noCreate)
-	if err != nil {
-		panic(err)
-	}
+	if check {
+		stale, err := gen.CheckDir(folder)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
 
-	os.Exit(0)
+		for _, f := range stale {
+			fmt.Println(f.String())
+		}
 
-	data, err := os.ReadFile("./example/pet.go")
-	if err != nil {
-		panic(err)
+		if len(stale) > 0 {
+			os.Exit(1)
+		}
+
+		return
 	}
 
-	fset := token.NewFileSet()
-	f, err := decorator.ParseFile(fset, "", data, parser.ParseComments)
+	err := gen.InspectDir(folder, noCreate)
 	if err != nil {
 		panic(err)
 	}
-
-	// record := false
-
-	for _, node := range f.Decls {
-		if decl, ok := node.(*dst.GenDecl); ok {
-			for _, spec := range decl.Specs {
-				if tt, ok := spec.(*dst.TypeSpec); ok {
-					if st, ok := tt.Type.(*dst.StructType); ok {
-						// fmt.Printf(":type: %s %v\n", tt.Name, st.Incomplete)
-
-						for _, f := range st.Fields.List {
-							// we found a sub structure, iterate on its fields
-							if sectionStruct, ok := f.Type.(*dst.StructType); ok {
-								for _, sectionFields := range sectionStruct.Fields.List {
-									fmt.Printf("%s[%s] %s\n", tt.Name, f.Names[0], sectionFields.Names[0])
-								}
-							}
-						}
-
-					}
-
-				}
-			}
-		}
-
-	}
-
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/schmurfy/chipi/mock"
+)
+
+// runMock serves a specification with the mock handler:
+//
+//	chipi-gen mock -spec ./openapi.json -addr :2121
+func runMock(args []string) error {
+	flags := flag.NewFlagSet("mock", flag.ExitOnError)
+
+	specLocation := flags.String("spec", "", "path or url of the specification to serve")
+	addr := flags.String("addr", ":2121", "address to listen on")
+	flags.Parse(args)
+
+	if *specLocation == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	spec, err := loadSpecification(*specLocation)
+	if err != nil {
+		return err
+	}
+
+	handler, err := mock.NewHandler(spec)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("serving %s on %s\n", *specLocation, *addr)
+	return http.ListenAndServe(*addr, handler)
+}
+
+func loadSpecification(location string) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		u, err := url.Parse(location)
+		if err != nil {
+			return nil, err
+		}
+
+		return loader.LoadFromURI(u)
+	}
+
+	return loader.LoadFromFile(location)
+}
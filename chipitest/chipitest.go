@@ -0,0 +1,245 @@
+// Package chipitest calls the operations of a builder in memory, the request objects
+// are serialized as a client would and go through the real router and wrapper:
+//
+//	res, err := chipitest.Call(api, &GetPetRequest{
+//		Path: struct{ Id int32 }{Id: 42},
+//	})
+//	require.NoError(t, err)
+//	assert.Equal(t, http.StatusOK, res.Status)
+//	assert.Equal(t, "Fido", res.Request.Response.Name)
+package chipitest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/schmurfy/chipi/builder"
+)
+
+// Result is the response to a call
+type Result[R any] struct {
+	Status int
+	Header http.Header
+	Body   []byte
+
+	// a copy of the request object with its Response field decoded from the body,
+	// only json and ndjson responses of successful calls are decoded
+	Request *R
+}
+
+// Decode decodes the json body into target, useful for the error responses
+func (r *Result[R]) Decode(target interface{}) error {
+	return json.Unmarshal(r.Body, target)
+}
+
+type options struct {
+	ctx         context.Context
+	header      http.Header
+	contentType string
+}
+
+// Option changes how a request is sent
+type Option func(*options)
+
+// WithContext sets the context of the request
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithHeader adds a header to the request (ex: Accept or Authorization)
+func WithHeader(name string, value string) Option {
+	return func(o *options) {
+		o.header.Add(name, value)
+	}
+}
+
+// WithContentType sends the body as contentType instead of the
+// one chosen from the decoders (json when possible)
+func WithContentType(contentType string) Option {
+	return func(o *options) {
+		o.contentType = contentType
+	}
+}
+
+// Call sends req to the operation registered for its type on b
+func Call[R any](b *builder.Builder, req *R, opts ...Option) (*Result[R], error) {
+	o := &options{
+		ctx:    context.Background(),
+		header: http.Header{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	r, err := NewRequest(o.ctx, b, req, o.contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range o.header {
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	b.Router().ServeHTTP(w, r)
+
+	ret := &Result[R]{
+		Status: w.Code,
+		Header: w.Header(),
+		Body:   w.Body.Bytes(),
+	}
+
+	// a copy, the decoded response must not change req
+	ret.Request = new(R)
+	*ret.Request = *req
+
+	if (ret.Status >= 200) && (ret.Status < 300) {
+		err = decodeResponse(ret.Header.Get("Content-Type"), ret.Body, reflect.ValueOf(ret.Request).Elem())
+		if err != nil {
+			return ret, fmt.Errorf("decoding response: %w", err)
+		}
+	}
+
+	return ret, nil
+}
+
+// ValidateResponses makes the test fail when a response of b does not match the
+// specification, see builder.ValidateResponses. The validation is disabled
+// when the test is done.
+func ValidateResponses(t testing.TB, b *builder.Builder) {
+	t.Helper()
+
+	b.ValidateResponses(func(ctx context.Context, violation *builder.ContractViolation) {
+		t.Errorf("%s", violation.Error())
+	})
+
+	t.Cleanup(func() {
+		b.ValidateResponses(nil)
+	})
+}
+
+// NewRequest returns the http request a client would send for req, its
+// Path, Query, Header and Body fields are serialized.
+func NewRequest(ctx context.Context, b *builder.Builder, req interface{}, contentType string) (*http.Request, error) {
+	method, pattern, err := b.RouteFor(req)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := reflect.ValueOf(req)
+	if (obj.Kind() != reflect.Ptr) || (obj.Elem().Kind() != reflect.Struct) {
+		return nil, fmt.Errorf("wrong type, pointer to struct expected")
+	}
+	obj = obj.Elem()
+
+	path, err := buildPath(pattern, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := buildQuery(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if bodyField, found := obj.Type().FieldByName("Body"); found {
+		if contentType == "" {
+			contentType = bodyContentType(req, bodyField)
+		}
+
+		body, contentType, err = encodeBody(contentType, obj.FieldByIndex(bodyField.Index))
+		if err != nil {
+			return nil, fmt.Errorf("encoding body: %w", err)
+		}
+	}
+
+	r := httptest.NewRequest(method, path, body).WithContext(ctx)
+	if body != nil {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	err = setHeaders(r, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// decodeResponse sets the Response field of obj from the body
+func decodeResponse(contentType string, body []byte, obj reflect.Value) error {
+	responseValue := obj.FieldByName("Response")
+	if !responseValue.IsValid() || (len(body) == 0) {
+		return nil
+	}
+
+	// the copy of the request can share pointers with the original
+	responseValue.Set(reflect.Zero(responseValue.Type()))
+
+	// raw responses
+	if responseValue.Type() == _bytesType {
+		responseValue.SetBytes(body)
+		return nil
+	}
+
+	// channels, sequences and streams cannot be decoded
+	switch responseValue.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface:
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case (mediaType == "application/json") || strings.HasSuffix(mediaType, "+json"):
+		return json.Unmarshal(body, responseValue.Addr().Interface())
+
+	case (mediaType == "application/x-ndjson") && (responseValue.Kind() == reflect.Slice):
+		ret := reflect.MakeSlice(responseValue.Type(), 0, 0)
+
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+
+			item := reflect.New(responseValue.Type().Elem())
+			err = json.Unmarshal(scanner.Bytes(), item.Interface())
+			if err != nil {
+				return err
+			}
+
+			ret = reflect.Append(ret, item.Elem())
+		}
+
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		responseValue.Set(ret)
+	}
+
+	return nil
+}
@@ -0,0 +1,334 @@
+package chipitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/builder"
+	"github.com/schmurfy/chipi/request"
+	"github.com/schmurfy/chipi/response"
+	"github.com/schmurfy/chipi/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type petKind string
+
+type petKindResolver struct{}
+
+func (r *petKindResolver) EnumResolver(t reflect.Type) (bool, shared.Enum) {
+	if t == reflect.TypeOf(petKind("")) {
+		return true, shared.Enum{{Title: "Dog", Value: "dog"}, {Title: "Cat", Value: "cat"}}
+	}
+	return false, nil
+}
+
+type pet struct {
+	Id    int       `json:"id"`
+	Name  string    `json:"name"`
+	Tags  []string  `json:"tags"`
+	Born  time.Time `json:"born"`
+	Owner string    `json:"owner"`
+	Kind  petKind   `json:"kind,omitempty"`
+}
+
+type getPetRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+
+	Path struct {
+		Id int
+	} `example:"/pets/1"`
+
+	Query struct {
+		Tags      []string
+		BornAfter *time.Time
+	}
+
+	Header struct {
+		Owner string `name:"X-Owner"`
+	}
+
+	Response pet
+}
+
+func (r *getPetRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	if r.Path.Id == 404 {
+		return errors.New("not found")
+	}
+
+	r.Response = pet{
+		Id:    r.Path.Id,
+		Name:  "rex",
+		Tags:  r.Query.Tags,
+		Owner: r.Header.Owner,
+	}
+
+	if r.Query.BornAfter != nil {
+		r.Response.Born = r.Query.BornAfter.Add(time.Hour)
+	}
+
+	return nil
+}
+
+type createPetRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+	request.JsonBodyDecoder
+
+	Path struct{} `example:"/pets"`
+
+	Body pet
+
+	Response pet
+}
+
+func (r *createPetRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	r.Response = r.Body
+	r.Response.Id = 1
+	return nil
+}
+
+type uploadPhotoRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+	request.MultipartBodyDecoder
+
+	Path struct {
+		Id int
+	} `example:"/pets/1/photo"`
+
+	Body struct {
+		Caption  string `json:"caption"`
+		Nickname string `json:"nickname" form:"nick"`
+		Photo    []byte `json:"picture" form:"photo"`
+	} `content-type:"multipart/form-data"`
+
+	Response struct {
+		Caption  string
+		Nickname string
+		Size     int
+	}
+}
+
+func (r *uploadPhotoRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	r.Response.Caption = r.Body.Caption
+	r.Response.Nickname = r.Body.Nickname
+	r.Response.Size = len(r.Body.Photo)
+	return nil
+}
+
+type listPetsRequest struct {
+	response.ErrorEncoder
+	response.NDJSONEncoder
+
+	Path struct{} `example:"/pets"`
+
+	Response []pet
+}
+
+func (r *listPetsRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	r.Response = []pet{{Id: 1, Name: "rex"}, {Id: 2, Name: "fido"}}
+	return nil
+}
+
+type downloadPhotoRequest struct {
+	response.ErrorEncoder
+
+	Path struct {
+		Id int
+	} `example:"/pets/1/photo"`
+}
+
+func (r *downloadPhotoRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	_, err := io.WriteString(w, "image")
+	return err
+}
+
+// records the failures of ValidateResponses and AssertSpecSnapshot
+type fakeT struct {
+	testing.TB
+
+	errors   []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+}
+
+func (t *fakeT) Logf(format string, args ...interface{}) {}
+
+func (t *fakeT) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+type invalidPetRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+
+	Path struct{} `example:"/invalid"`
+
+	Response pet
+}
+
+// only 200 is documented
+func (r *invalidPetRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func TestChipitest(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Call", func() {
+		var b *builder.Builder
+
+		g.BeforeEach(func() {
+			var err error
+
+			router := chi.NewRouter()
+			b, err = builder.New(router, &openapi3.Info{})
+			require.NoError(g, err)
+
+			petRouter := chi.NewRouter()
+			router.Mount("/pets", petRouter)
+
+			require.NoError(g, b.Get(petRouter, "/{Id}", &getPetRequest{}))
+			require.NoError(g, b.Post(petRouter, "/", &createPetRequest{}))
+			require.NoError(g, b.Put(petRouter, "/{Id}/photo", &uploadPhotoRequest{}))
+			require.NoError(g, b.Get(petRouter, "/", &listPetsRequest{}))
+		})
+
+		g.It("should send path, query and header parameters", func() {
+			born := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			req := &getPetRequest{}
+			req.Path.Id = 42
+			req.Query.Tags = []string{"a", "b"}
+			req.Query.BornAfter = &born
+			req.Header.Owner = "john"
+
+			res, err := Call(b, req)
+			require.NoError(g, err)
+
+			require.Equal(g, http.StatusOK, res.Status)
+			assert.Equal(g, "application/json", res.Header.Get("Content-Type"))
+			assert.Equal(g, pet{
+				Id:    42,
+				Name:  "rex",
+				Tags:  []string{"a", "b"},
+				Born:  born.Add(time.Hour),
+				Owner: "john",
+			}, res.Request.Response)
+
+			// the request is left untouched
+			assert.Equal(g, pet{}, req.Response)
+		})
+
+		g.It("should return the error responses", func() {
+			req := &getPetRequest{}
+			req.Path.Id = 404
+
+			res, err := Call(b, req)
+			require.NoError(g, err)
+
+			assert.Equal(g, http.StatusBadRequest, res.Status)
+			assert.Equal(g, "not found\n", string(res.Body))
+			assert.Equal(g, pet{}, res.Request.Response)
+		})
+
+		g.It("should send json bodies", func() {
+			res, err := Call(b, &createPetRequest{
+				Body: pet{Name: "fido", Tags: []string{"small"}},
+			})
+			require.NoError(g, err)
+
+			require.Equal(g, http.StatusOK, res.Status)
+			assert.Equal(g, pet{Id: 1, Name: "fido", Tags: []string{"small"}}, res.Request.Response)
+		})
+
+		g.It("should send multipart bodies", func() {
+			req := &uploadPhotoRequest{}
+			req.Path.Id = 3
+			req.Body.Caption = "sleeping"
+			req.Body.Nickname = "felix"
+			req.Body.Photo = []byte("not really a photo")
+
+			res, err := Call(b, req)
+			require.NoError(g, err)
+
+			require.Equal(g, http.StatusOK, res.Status)
+			assert.Equal(g, "sleeping", res.Request.Response.Caption)
+			assert.Equal(g, "felix", res.Request.Response.Nickname)
+			assert.Equal(g, 18, res.Request.Response.Size)
+		})
+
+		g.It("should decode ndjson responses", func() {
+			res, err := Call(b, &listPetsRequest{})
+			require.NoError(g, err)
+
+			require.Equal(g, http.StatusOK, res.Status)
+			assert.Equal(g, []pet{{Id: 1, Name: "rex"}, {Id: 2, Name: "fido"}}, res.Request.Response)
+		})
+
+		g.It("should add the headers", func() {
+			req := &getPetRequest{}
+			req.Path.Id = 1
+
+			res, err := Call(b, req, WithHeader("X-Owner", "jane"))
+			require.NoError(g, err)
+
+			require.Equal(g, http.StatusOK, res.Status)
+			assert.Equal(g, "jane", res.Request.Response.Owner)
+		})
+
+		g.It("should fail the test when a response does not match the specification", func() {
+			require.NoError(g, b.Get(b.Router(), "/invalid", &invalidPetRequest{}))
+
+			ft := &fakeT{}
+			ValidateResponses(ft, b)
+
+			// nil slices would be encoded as null
+			res, err := Call(b, &createPetRequest{Body: pet{Name: "fido", Tags: []string{}}})
+			require.NoError(g, err)
+			require.Equal(g, http.StatusOK, res.Status)
+			assert.Empty(g, ft.errors)
+
+			invalid, err := Call(b, &invalidPetRequest{})
+			require.NoError(g, err)
+			require.Equal(g, http.StatusCreated, invalid.Status)
+			require.Len(g, ft.errors, 1)
+			assert.Contains(g, ft.errors[0], "invalidPetRequest")
+
+			for _, fn := range ft.cleanups {
+				fn()
+			}
+
+			_, _ = Call(b, &invalidPetRequest{})
+			assert.Len(g, ft.errors, 1)
+		})
+
+		g.It("should return an error for unregistered requests", func() {
+			_, err := Call(b, &downloadPhotoRequest{})
+			require.Error(g, err)
+			assert.Contains(g, err.Error(), "not registered")
+		})
+	})
+}
@@ -0,0 +1,414 @@
+package chipitest
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schmurfy/chipi/request"
+	"github.com/schmurfy/chipi/schema"
+	"github.com/schmurfy/chipi/shared"
+	"github.com/schmurfy/chipi/wrapper"
+)
+
+var (
+	_textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	_durationType      = reflect.TypeOf(time.Duration(0))
+	_fileHeaderType    = reflect.TypeOf((*multipart.FileHeader)(nil))
+	_readerType        = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	_bytesType         = reflect.TypeOf([]byte(nil))
+)
+
+// formatValue is the reverse of shared.ConvertValue, it returns false for nil pointers
+func formatValue(v reflect.Value) (string, bool, error) {
+	for (v.Kind() == reflect.Ptr) || (v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		v = v.Elem()
+	}
+
+	// types knowing how to format themselves (ex: time.Time, netip.Addr)
+	if v.Type().Implements(_textMarshalerType) || reflect.PointerTo(v.Type()).Implements(_textMarshalerType) {
+		if !v.CanAddr() {
+			ptr := reflect.New(v.Type())
+			ptr.Elem().Set(v)
+			v = ptr.Elem()
+		}
+
+		marshaler, ok := v.Interface().(encoding.TextMarshaler)
+		if !ok {
+			marshaler = v.Addr().Interface().(encoding.TextMarshaler)
+		}
+
+		data, err := marshaler.MarshalText()
+		return string(data), true, err
+	}
+
+	if v.Type() == _durationType {
+		return time.Duration(v.Int()).String(), true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			value, _, err := formatValue(v.Index(i))
+			if err != nil {
+				return "", false, err
+			}
+			values = append(values, value)
+		}
+		return strings.Join(values, ","), true, nil
+
+	case reflect.Map, reflect.Struct:
+		data, err := json.Marshal(v.Interface())
+		return string(data), true, err
+
+	case reflect.String:
+		return v.String(), true, nil
+
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), true, nil
+	}
+
+	return "", false, fmt.Errorf("invalid type: %v", v.Kind())
+}
+
+// buildPath replaces the parameters of pattern (ex: /pet/{Id}) by the
+// values of the Path structure
+func buildPath(pattern string, obj reflect.Value) (string, error) {
+	pathValue := obj.FieldByName("Path")
+
+	ret := &strings.Builder{}
+	for {
+		start := strings.Index(pattern, "{")
+		if start == -1 {
+			break
+		}
+
+		end := strings.Index(pattern[start:], "}")
+		if end == -1 {
+			return "", fmt.Errorf("invalid pattern: %s", pattern)
+		}
+		end += start
+
+		// the parameters can have a regexp (ex: {Id:[0-9]+})
+		name, _, _ := strings.Cut(pattern[start+1:end], ":")
+
+		if !pathValue.IsValid() {
+			return "", fmt.Errorf("no Path structure for parameter %s", name)
+		}
+
+		field := pathValue.FieldByName(name)
+		if !field.IsValid() {
+			return "", fmt.Errorf("path parameter %s not found in Path", name)
+		}
+
+		value, _, err := formatValue(field)
+		if err != nil {
+			return "", fmt.Errorf("path parameter %s: %w", name, err)
+		}
+
+		ret.WriteString(pattern[:start])
+		ret.WriteString(url.PathEscape(value))
+		pattern = pattern[end+1:]
+	}
+
+	ret.WriteString(pattern)
+	return strings.TrimSuffix(ret.String(), "*"), nil
+}
+
+// buildQuery encodes the non zero fields of the Query structure
+func buildQuery(obj reflect.Value) (url.Values, error) {
+	ret := url.Values{}
+
+	queryValue := obj.FieldByName("Query")
+	if !queryValue.IsValid() {
+		return ret, nil
+	}
+
+	for _, structField := range reflect.VisibleFields(queryValue.Type()) {
+		if !structField.IsExported() || structField.Anonymous {
+			continue
+		}
+
+		// same rules as the wrapper
+		name := schema.ParseJsonTag(structField).Name
+		if name == structField.Name {
+			name = shared.ToSnakeCase(structField.Name)
+		}
+
+		field := queryValue.FieldByIndex(structField.Index)
+		if field.IsZero() {
+			continue
+		}
+
+		value, found, err := formatValue(field)
+		if err != nil {
+			return nil, fmt.Errorf("query parameter %s: %w", name, err)
+		}
+
+		if found {
+			ret.Set(name, value)
+		}
+	}
+
+	return ret, nil
+}
+
+// setHeaders sets the non zero fields of the Header structure on r
+func setHeaders(r *http.Request, obj reflect.Value) error {
+	headerValue := obj.FieldByName("Header")
+	if !headerValue.IsValid() {
+		return nil
+	}
+
+	for i := 0; i < headerValue.NumField(); i++ {
+		structField := headerValue.Type().Field(i)
+
+		name := structField.Tag.Get("name")
+		if name == "" {
+			name = structField.Name
+		}
+
+		field := headerValue.Field(i)
+		if field.IsZero() {
+			continue
+		}
+
+		value, found, err := formatValue(field)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", name, err)
+		}
+
+		if found {
+			r.Header.Set(name, value)
+		}
+	}
+
+	return nil
+}
+
+// bodyContentType returns the content type a client would use to send the body
+func bodyContentType(obj interface{}, bodyField reflect.StructField) string {
+	if decoders, ok := obj.(wrapper.BodyDecodersInterface); ok {
+		contentTypes := shared.SortedContentTypes(decoders.BodyDecoders())
+		for _, contentType := range contentTypes {
+			if contentType == "application/json" {
+				return contentType
+			}
+		}
+
+		for _, contentType := range contentTypes {
+			if !strings.Contains(contentType, "*") {
+				return contentType
+			}
+		}
+
+		return "application/json"
+	}
+
+	if contentType, found := bodyField.Tag.Lookup("content-type"); found {
+		return contentType
+	}
+
+	return "application/json"
+}
+
+// encodeBody serializes the Body field as contentType, the returned
+// content type includes the multipart boundary
+func encodeBody(contentType string, body reflect.Value) (io.Reader, string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case body.Type() == _bytesType:
+		return bytes.NewReader(body.Bytes()), contentType, nil
+
+	case (mediaType == "application/json") || strings.HasSuffix(mediaType, "+json"):
+		data, err := json.Marshal(body.Interface())
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(data), contentType, nil
+
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := formValues(body, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		return strings.NewReader(values.Encode()), contentType, nil
+
+	case mediaType == "multipart/form-data":
+		buffer := &bytes.Buffer{}
+		writer := multipart.NewWriter(buffer)
+
+		values, err := formValues(body, writer)
+		if err != nil {
+			return nil, "", err
+		}
+
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			for _, value := range values[key] {
+				err = writer.WriteField(key, value)
+				if err != nil {
+					return nil, "", err
+				}
+			}
+		}
+
+		err = writer.Close()
+		if err != nil {
+			return nil, "", err
+		}
+
+		return buffer, writer.FormDataContentType(), nil
+	}
+
+	return nil, "", fmt.Errorf("cannot encode a body as %s", contentType)
+}
+
+// formValues returns the fields of a form body by json name, the
+// files are written to files if set
+func formValues(body reflect.Value, files *multipart.Writer) (url.Values, error) {
+	for body.Kind() == reflect.Ptr {
+		if body.IsNil() {
+			return url.Values{}, nil
+		}
+		body = body.Elem()
+	}
+
+	if body.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("form body must be a structure, got %s", body.Kind())
+	}
+
+	ret := url.Values{}
+	for _, f := range reflect.VisibleFields(body.Type()) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+
+		// same names as the form decoder
+		name := request.FormFieldName(f)
+		if name == "-" {
+			continue
+		}
+
+		fv, err := body.FieldByIndexErr(f.Index)
+		if (err != nil) || fv.IsZero() {
+			continue
+		}
+
+		if (files != nil) && request.IsFileType(f.Type) {
+			err = writeFiles(files, name, fv)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			continue
+		}
+
+		// []byte sent as a simple value and repeated fields
+		switch {
+		case fv.Type() == _bytesType:
+			ret.Add(name, string(fv.Bytes()))
+
+		case fv.Kind() == reflect.Slice:
+			for i := 0; i < fv.Len(); i++ {
+				value, found, err := formatValue(fv.Index(i))
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				if found {
+					ret.Add(name, value)
+				}
+			}
+
+		default:
+			value, found, err := formatValue(fv)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if found {
+				ret.Add(name, value)
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+func writeFiles(w *multipart.Writer, name string, fv reflect.Value) error {
+	if (fv.Kind() == reflect.Slice) && (fv.Type() != _bytesType) {
+		for i := 0; i < fv.Len(); i++ {
+			err := writeFile(w, name, fv.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return writeFile(w, name, fv)
+}
+
+func writeFile(w *multipart.Writer, name string, fv reflect.Value) error {
+	filename := name
+	var content io.Reader
+
+	switch fv.Type() {
+	case _bytesType:
+		content = bytes.NewReader(fv.Bytes())
+
+	case _readerType:
+		content = fv.Interface().(io.Reader)
+
+	case _fileHeaderType:
+		h := fv.Interface().(*multipart.FileHeader)
+		file, err := h.Open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		filename = h.Filename
+		content = file
+	}
+
+	part, err := w.CreateFormFile(name, filename)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, content)
+	return err
+}
@@ -0,0 +1,127 @@
+package chipitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/schmurfy/chipi/builder"
+)
+
+// UpdateSnapshotsEnv rewrites the snapshots when set to "1" or "true"
+const UpdateSnapshotsEnv = "CHIPI_UPDATE_SNAPSHOTS"
+
+// updateSnapshots returns true if the snapshots must be rewritten, the update flag
+// is not registered by the package but used when the tests define it
+func updateSnapshots() bool {
+	switch os.Getenv(UpdateSnapshotsEnv) {
+	case "1", "true":
+		return true
+	}
+
+	f := flag.Lookup("update")
+	return (f != nil) && (f.Value.String() == "true")
+}
+
+// AssertSpecSnapshot compares the specification served by b with the golden file
+// at path, the test fails when they differ. Running the tests with
+// CHIPI_UPDATE_SNAPSHOTS=1 (or -update if the tests define the flag) rewrites the
+// file instead:
+//
+//	CHIPI_UPDATE_SNAPSHOTS=1 go test ./api -run TestSpecification
+func AssertSpecSnapshot(t testing.TB, b *builder.Builder, path string) {
+	t.Helper()
+
+	data, err := SpecSnapshot(context.Background(), b)
+	if err != nil {
+		t.Fatalf("generating the specification: %s", err.Error())
+		return
+	}
+
+	if updateSnapshots() {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		if err != nil {
+			t.Fatalf("updating %s: %s", path, err.Error())
+			return
+		}
+
+		t.Logf("%s updated", path)
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("%s does not exist, run the tests with %s=1 to create it", path, UpdateSnapshotsEnv)
+		return
+	}
+	if err != nil {
+		t.Fatalf("reading %s: %s", path, err.Error())
+		return
+	}
+
+	if !bytes.Equal(expected, data) {
+		t.Errorf("the specification does not match %s, run the tests with %s=1 if the change is expected\n%s",
+			path, UpdateSnapshotsEnv, firstDifference(expected, data))
+	}
+}
+
+// SpecSnapshot returns the specification served by b as indented json, the keys are
+// sorted so the same operations always produce the same document.
+func SpecSnapshot(ctx context.Context, b *builder.Builder) ([]byte, error) {
+	data, err := b.SchemaJson(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the maps are encoded with sorted keys
+	var doc interface{}
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	err = enc.Encode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// firstDifference describes the first line differing between the two documents
+func firstDifference(expected []byte, actual []byte) string {
+	expectedLines := strings.Split(string(expected), "\n")
+	actualLines := strings.Split(string(actual), "\n")
+
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var expectedLine, actualLine string
+		if i < len(expectedLines) {
+			expectedLine = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			actualLine = actualLines[i]
+		}
+
+		if expectedLine != actualLine {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, strings.TrimSpace(expectedLine), strings.TrimSpace(actualLine))
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,114 @@
+package chipitest
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/builder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// defined by the tests, as the users of the package would
+var _ = flag.Bool("update", false, "rewrite the snapshots")
+
+func TestSnapshot(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("AssertSpecSnapshot", func() {
+		var b *builder.Builder
+		var path string
+
+		g.BeforeEach(func() {
+			var err error
+
+			router := chi.NewRouter()
+			b, err = builder.New(router, &openapi3.Info{Title: "pets"})
+			require.NoError(g, err)
+
+			require.NoError(g, b.Get(router, "/pets/{Id}", &getPetRequest{}))
+			require.NoError(g, b.Post(router, "/pets", &createPetRequest{}))
+
+			path = filepath.Join(t.TempDir(), "testdata", "openapi.json")
+		})
+
+		update := func(fn func()) {
+			require.NoError(g, os.Setenv(UpdateSnapshotsEnv, "1"))
+			defer os.Unsetenv(UpdateSnapshotsEnv)
+
+			fn()
+		}
+
+		g.It("should generate the same document every time", func() {
+			first, err := SpecSnapshot(context.Background(), b)
+			require.NoError(g, err)
+
+			second, err := SpecSnapshot(context.Background(), b)
+			require.NoError(g, err)
+
+			assert.Equal(g, string(first), string(second))
+		})
+
+		g.It("should fail when the snapshot does not exist", func() {
+			ft := &fakeT{}
+			AssertSpecSnapshot(ft, b, path)
+
+			require.Len(g, ft.errors, 1)
+			assert.Contains(g, ft.errors[0], UpdateSnapshotsEnv)
+		})
+
+		g.It("should write the snapshot with the environment variable", func() {
+			ft := &fakeT{}
+			update(func() {
+				AssertSpecSnapshot(ft, b, path)
+			})
+			assert.Empty(g, ft.errors)
+
+			data, err := os.ReadFile(path)
+			require.NoError(g, err)
+			assert.Contains(g, string(data), `"/pets/{Id}"`)
+
+			AssertSpecSnapshot(ft, b, path)
+			assert.Empty(g, ft.errors)
+		})
+
+		g.It("should write the snapshot with the update flag of the tests", func() {
+			require.NoError(g, flag.Set("update", "true"))
+			defer flag.Set("update", "false")
+
+			ft := &fakeT{}
+			AssertSpecSnapshot(ft, b, path)
+			assert.Empty(g, ft.errors)
+
+			_, err := os.Stat(path)
+			require.NoError(g, err)
+		})
+
+		g.It("should use the enum resolver of the builder", func() {
+			b.SetEnumResolver(&petKindResolver{})
+
+			data, err := SpecSnapshot(context.Background(), b)
+			require.NoError(g, err)
+			assert.Contains(g, string(data), `"enum": [`)
+		})
+
+		g.It("should fail when the specification changed", func() {
+			ft := &fakeT{}
+			update(func() {
+				AssertSpecSnapshot(ft, b, path)
+			})
+
+			require.NoError(g, b.Get(b.Router(), "/pets", &listPetsRequest{}))
+
+			AssertSpecSnapshot(ft, b, path)
+			require.Len(g, ft.errors, 1)
+			assert.Contains(g, ft.errors[0], "does not match")
+		})
+	})
+}
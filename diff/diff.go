@@ -0,0 +1,324 @@
+// Package diff compares two versions of a specification and classifies the
+// changes as breaking or not for the existing clients:
+//
+//	report := diff.Compare(published, generated)
+//	if report.HasBreaking() {
+//		report.Write(os.Stderr)
+//		os.Exit(1)
+//	}
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/schmurfy/chipi/shared"
+)
+
+// Change is a difference between the two specifications
+type Change struct {
+	// the clients written for the old specification may fail
+	Breaking bool
+
+	// where the change is (ex: "GET /pets/{Id} request.query.Limit")
+	Location string
+	Message  string
+}
+
+func (c Change) String() string {
+	kind := "non-breaking"
+	if c.Breaking {
+		kind = "breaking"
+	}
+
+	return fmt.Sprintf("[%s] %s: %s", kind, c.Location, c.Message)
+}
+
+// Report lists the changes in the order of the paths, methods and fields
+type Report struct {
+	Changes []Change
+}
+
+// Breaking returns the breaking changes
+func (r *Report) Breaking() []Change {
+	ret := []Change{}
+	for _, c := range r.Changes {
+		if c.Breaking {
+			ret = append(ret, c)
+		}
+	}
+
+	return ret
+}
+
+// HasBreaking returns true if one change at least is breaking
+func (r *Report) HasBreaking() bool {
+	return len(r.Breaking()) > 0
+}
+
+// Write writes the changes, one per line, followed by a summary
+func (r *Report) Write(w io.Writer) error {
+	for _, c := range r.Changes {
+		_, err := fmt.Fprintln(w, c.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	breaking := len(r.Breaking())
+	_, err := fmt.Fprintf(w, "%d changes, %d breaking\n", len(r.Changes), breaking)
+	return err
+}
+
+// Compare returns the changes from base to revision, both must be documents
+// produced by GenerateSwagger (or loaded from their json).
+func Compare(base *openapi3.T, revision *openapi3.T) *Report {
+	d := &differ{
+		base:     base,
+		revision: revision,
+		report:   &Report{},
+	}
+
+	d.comparePaths()
+
+	return d.report
+}
+
+type differ struct {
+	base     *openapi3.T
+	revision *openapi3.T
+	report   *Report
+
+	// the schemas being compared, the recursive ones are only compared once
+	visiting map[schemaPair]bool
+}
+
+func (d *differ) add(breaking bool, location string, format string, args ...interface{}) {
+	d.report.Changes = append(d.report.Changes, Change{
+		Breaking: breaking,
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *differ) comparePaths() {
+	basePaths := pathsMap(d.base)
+	revisionPaths := pathsMap(d.revision)
+
+	for _, path := range sortedKeys(basePaths, revisionPaths) {
+		baseItem, revisionItem := basePaths[path], revisionPaths[path]
+
+		baseOperations := operationsMap(baseItem)
+		revisionOperations := operationsMap(revisionItem)
+
+		for _, method := range sortedKeys(baseOperations, revisionOperations) {
+			location := method + " " + path
+			baseOp, revisionOp := baseOperations[method], revisionOperations[method]
+
+			switch {
+			case revisionOp == nil:
+				d.add(true, location, "operation removed")
+			case baseOp == nil:
+				d.add(false, location, "operation added")
+			default:
+				d.compareOperation(location, baseOp, revisionOp)
+			}
+		}
+	}
+}
+
+func (d *differ) compareOperation(location string, base *openapi3.Operation, revision *openapi3.Operation) {
+	if !base.Deprecated && revision.Deprecated {
+		d.add(false, location, "operation deprecated")
+	}
+
+	d.compareParameters(location, base.Parameters, revision.Parameters)
+	d.compareRequestBody(location, base.RequestBody, revision.RequestBody)
+	d.compareResponses(location, base.Responses, revision.Responses)
+}
+
+func (d *differ) compareParameters(location string, base openapi3.Parameters, revision openapi3.Parameters) {
+	baseParams := parametersMap(base)
+	revisionParams := parametersMap(revision)
+
+	for _, key := range sortedKeys(baseParams, revisionParams) {
+		paramLocation := location + " request." + key
+		baseParam, revisionParam := baseParams[key], revisionParams[key]
+
+		switch {
+		case revisionParam == nil:
+			d.add(true, paramLocation, "parameter removed")
+
+		case baseParam == nil:
+			if revisionParam.Required {
+				d.add(true, paramLocation, "required parameter added")
+			} else {
+				d.add(false, paramLocation, "optional parameter added")
+			}
+
+		default:
+			if !baseParam.Required && revisionParam.Required {
+				d.add(true, paramLocation, "parameter is now required")
+			} else if baseParam.Required && !revisionParam.Required {
+				d.add(false, paramLocation, "parameter is now optional")
+			}
+
+			d.compareSchemas(paramLocation, inRequest, baseParam.Schema, revisionParam.Schema)
+		}
+	}
+}
+
+func (d *differ) compareRequestBody(location string, base *openapi3.RequestBodyRef, revision *openapi3.RequestBodyRef) {
+	bodyLocation := location + " request.body"
+	baseBody, revisionBody := requestBodyValue(base), requestBodyValue(revision)
+
+	switch {
+	case (baseBody == nil) && (revisionBody == nil):
+		return
+
+	case revisionBody == nil:
+		d.add(true, bodyLocation, "body removed")
+		return
+
+	case baseBody == nil:
+		if revisionBody.Required {
+			d.add(true, bodyLocation, "required body added")
+		} else {
+			d.add(false, bodyLocation, "optional body added")
+		}
+		return
+	}
+
+	if !baseBody.Required && revisionBody.Required {
+		d.add(true, bodyLocation, "body is now required")
+	}
+
+	d.compareContent(bodyLocation, inRequest, baseBody.Content, revisionBody.Content)
+}
+
+func (d *differ) compareResponses(location string, base *openapi3.Responses, revision *openapi3.Responses) {
+	baseResponses := responsesMap(base)
+	revisionResponses := responsesMap(revision)
+
+	for _, status := range sortedKeys(baseResponses, revisionResponses) {
+		responseLocation := location + " response." + status
+		baseResponse, revisionResponse := baseResponses[status], revisionResponses[status]
+
+		switch {
+		case revisionResponse == nil:
+			d.add(true, responseLocation, "response removed")
+		case baseResponse == nil:
+			d.add(false, responseLocation, "response added")
+		default:
+			d.compareContent(responseLocation, inResponse, baseResponse.Content, revisionResponse.Content)
+		}
+	}
+}
+
+func (d *differ) compareContent(location string, dir direction, base openapi3.Content, revision openapi3.Content) {
+	for _, contentType := range shared.SortedContentTypes(mergeContent(base, revision)) {
+		contentLocation := fmt.Sprintf("%s (%s)", location, contentType)
+		baseMedia, revisionMedia := base[contentType], revision[contentType]
+
+		switch {
+		case revisionMedia == nil:
+			d.add(true, contentLocation, "content type removed")
+		case baseMedia == nil:
+			d.add(false, contentLocation, "content type added")
+		default:
+			d.compareSchemas(contentLocation, dir, baseMedia.Schema, revisionMedia.Schema)
+		}
+	}
+}
+
+func pathsMap(doc *openapi3.T) map[string]*openapi3.PathItem {
+	if (doc == nil) || (doc.Paths == nil) {
+		return map[string]*openapi3.PathItem{}
+	}
+
+	return doc.Paths.Map()
+}
+
+func operationsMap(item *openapi3.PathItem) map[string]*openapi3.Operation {
+	if item == nil {
+		return map[string]*openapi3.Operation{}
+	}
+
+	return item.Operations()
+}
+
+// parametersMap indexes the parameters by location and name (ex: "query.Limit")
+func parametersMap(params openapi3.Parameters) map[string]*openapi3.Parameter {
+	ret := map[string]*openapi3.Parameter{}
+	for _, ref := range params {
+		if (ref == nil) || (ref.Value == nil) {
+			continue
+		}
+
+		name := ref.Value.Name
+		// the headers are case insensitive
+		if ref.Value.In == openapi3.ParameterInHeader {
+			name = strings.ToLower(name)
+		}
+
+		ret[ref.Value.In+"."+name] = ref.Value
+	}
+
+	return ret
+}
+
+func requestBodyValue(ref *openapi3.RequestBodyRef) *openapi3.RequestBody {
+	if ref == nil {
+		return nil
+	}
+
+	return ref.Value
+}
+
+func responsesMap(responses *openapi3.Responses) map[string]*openapi3.Response {
+	ret := map[string]*openapi3.Response{}
+	if responses == nil {
+		return ret
+	}
+
+	for status, ref := range responses.Map() {
+		if (ref != nil) && (ref.Value != nil) {
+			ret[status] = ref.Value
+		}
+	}
+
+	return ret
+}
+
+func mergeContent(base openapi3.Content, revision openapi3.Content) openapi3.Content {
+	ret := openapi3.Content{}
+	for contentType, media := range base {
+		ret[contentType] = media
+	}
+	for contentType, media := range revision {
+		ret[contentType] = media
+	}
+
+	return ret
+}
+
+// sortedKeys returns the keys found in any of the maps
+func sortedKeys[T any](maps ...map[string]T) []string {
+	found := map[string]bool{}
+	ret := []string{}
+
+	for _, m := range maps {
+		for key := range m {
+			if !found[key] {
+				found[key] = true
+				ret = append(ret, key)
+			}
+		}
+	}
+
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,246 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/builder"
+	"github.com/schmurfy/chipi/response"
+	"github.com/schmurfy/chipi/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type petV1 struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+}
+
+type getPetV1Request struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+
+	Path struct {
+		Id int
+	} `example:"/pets/1"`
+
+	Query struct {
+		Verbose bool
+	}
+
+	Response petV1
+}
+
+func (r *getPetV1Request) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+type petV2 struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type getPetV2Request struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+
+	Path struct {
+		Id int
+	} `example:"/pets/1"`
+
+	Query struct {
+		Verbose bool
+		Fields  []string `chipi:"required"`
+	}
+
+	Response petV2
+}
+
+func (r *getPetV2Request) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+type owner struct {
+	Name string `json:"name"`
+}
+
+type ownedPet struct {
+	Owner owner `json:"owner"`
+}
+
+type deprecatedOwnerPet struct {
+	Owner owner `json:"owner" chipi:"deprecated"`
+}
+
+type nullableOwnerPet struct {
+	Owner owner `json:"owner" chipi:"nullable"`
+}
+
+type readOnlyOwnerPet struct {
+	Owner owner `json:"owner" chipi:"readonly"`
+}
+
+type getOwnedPetRequest[T any] struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+
+	Path     struct{} `example:"/pets"`
+	Response T
+}
+
+func (r *getOwnedPetRequest[T]) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+func generate(g *goblin.G, pattern string, reqObject interface{}) *openapi3.T {
+	router := chi.NewRouter()
+
+	b, err := builder.New(router, &openapi3.Info{})
+	require.NoError(g, err)
+	require.NoError(g, b.Get(router, pattern, reqObject))
+
+	doc, err := b.GenerateSwagger(context.Background(), shared.NewChipiCallbacks(nil))
+	require.NoError(g, err)
+
+	return doc
+}
+
+func load(g *goblin.G, data string) *openapi3.T {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(data))
+	require.NoError(g, err)
+	return doc
+}
+
+// a document with a schema used by a query parameter and a response
+func enumDocument(values string) string {
+	return `{
+		"openapi": "3.1.0",
+		"info": {"title": "pets", "version": "1"},
+		"paths": {
+			"/pets": {
+				"get": {
+					"parameters": [
+						{"name": "Kind", "in": "query", "schema": {"type": "string", "enum": ` + values + `}}
+					],
+					"responses": {
+						"200": {
+							"description": "the pets",
+							"content": {
+								"application/json": {
+									"schema": {"type": "object", "properties": {"kind": {"type": "string", "enum": ` + values + `}}}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}`
+}
+
+func TestDiff(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Compare", func() {
+		g.It("should not report identical documents", func() {
+			base := generate(g, "/pets/{Id}", &getPetV1Request{})
+			revision := generate(g, "/pets/{Id}", &getPetV1Request{})
+
+			report := Compare(base, revision)
+			assert.Empty(g, report.Changes)
+			assert.False(g, report.HasBreaking())
+		})
+
+		g.It("should classify the changes of generated documents", func() {
+			base := generate(g, "/pets/{Id}", &getPetV1Request{})
+			revision := generate(g, "/pets/{Id}", &getPetV2Request{})
+
+			report := Compare(base, revision)
+
+			assert.Equal(g, []Change{
+				{Breaking: true, Location: "GET /pets/{Id} request.query.fields", Message: "required parameter added"},
+				{Breaking: false, Location: "GET /pets/{Id} response.200 (application/json).color", Message: "property added"},
+				{Breaking: true, Location: "GET /pets/{Id} response.200 (application/json).id", Message: "type changed from integer to string"},
+				{Breaking: true, Location: "GET /pets/{Id} response.200 (application/json).owner", Message: "property removed"},
+			}, report.Changes)
+		})
+
+		g.It("should compare the annotated references with their schema", func() {
+			base := generate(g, "/pets", &getOwnedPetRequest[ownedPet]{})
+
+			report := Compare(base, generate(g, "/pets", &getOwnedPetRequest[deprecatedOwnerPet]{}))
+			assert.Empty(g, report.Changes)
+
+			report = Compare(base, generate(g, "/pets", &getOwnedPetRequest[readOnlyOwnerPet]{}))
+			assert.Empty(g, report.Changes)
+
+			report = Compare(base, generate(g, "/pets", &getOwnedPetRequest[nullableOwnerPet]{}))
+			assert.Equal(g, []Change{
+				{Breaking: true, Location: "GET /pets response.200 (application/json).owner", Message: "is now nullable"},
+			}, report.Changes)
+
+			report = Compare(generate(g, "/pets", &getOwnedPetRequest[nullableOwnerPet]{}), base)
+			assert.Equal(g, []Change{
+				{Breaking: false, Location: "GET /pets response.200 (application/json).owner", Message: "is no longer nullable"},
+			}, report.Changes)
+		})
+
+		g.It("should not fail on documents without components", func() {
+			base := generate(g, "/pets", &getOwnedPetRequest[ownedPet]{})
+			revision := generate(g, "/pets", &getOwnedPetRequest[ownedPet]{})
+			revision.Components = nil
+
+			report := Compare(base, revision)
+			assert.True(g, report.HasBreaking())
+		})
+
+		g.It("should report the removed and added operations", func() {
+			base := generate(g, "/pets/{Id}", &getPetV1Request{})
+			revision := generate(g, "/pets/{Id}", &getPetV1Request{})
+			revision.Paths.Set("/animals/{Id}", revision.Paths.Value("/pets/{Id}"))
+			revision.Paths.Delete("/pets/{Id}")
+
+			report := Compare(base, revision)
+			assert.Equal(g, []Change{
+				{Breaking: false, Location: "GET /animals/{Id}", Message: "operation added"},
+				{Breaking: true, Location: "GET /pets/{Id}", Message: "operation removed"},
+			}, report.Changes)
+		})
+
+		g.It("should depend on the direction for the enums", func() {
+			base := load(g, enumDocument(`["cat", "dog"]`))
+
+			narrowed := Compare(base, load(g, enumDocument(`["cat"]`)))
+			assert.Equal(g, []Change{
+				{Breaking: true, Location: "GET /pets request.query.Kind", Message: "enum values removed: dog"},
+				{Breaking: false, Location: "GET /pets response.200 (application/json).kind", Message: "enum values removed: dog"},
+			}, narrowed.Changes)
+
+			widened := Compare(base, load(g, enumDocument(`["cat", "dog", "bird"]`)))
+			assert.Equal(g, []Change{
+				{Breaking: false, Location: "GET /pets request.query.Kind", Message: "enum values added: bird"},
+				{Breaking: true, Location: "GET /pets response.200 (application/json).kind", Message: "enum values added: bird"},
+			}, widened.Changes)
+		})
+
+		g.It("should write a readable report", func() {
+			base := load(g, enumDocument(`["cat", "dog"]`))
+			report := Compare(base, load(g, enumDocument(`["cat"]`)))
+
+			buf := &bytes.Buffer{}
+			require.NoError(g, report.Write(buf))
+
+			assert.Equal(g, ""+
+				"[breaking] GET /pets request.query.Kind: enum values removed: dog\n"+
+				"[non-breaking] GET /pets response.200 (application/json).kind: enum values removed: dog\n"+
+				"2 changes, 1 breaking\n", buf.String())
+		})
+	})
+}
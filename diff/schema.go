@@ -0,0 +1,348 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// direction tells who produces the values of a schema, the same change
+// breaks the clients or not depending on it (ex: a new enum value)
+type direction int
+
+const (
+	// sent by the clients
+	inRequest direction = iota
+	// read by the clients
+	inResponse
+)
+
+type schemaPair struct {
+	base     *openapi3.Schema
+	revision *openapi3.Schema
+	dir      direction
+}
+
+func (d *differ) compareSchemas(location string, dir direction, baseRef *openapi3.SchemaRef, revisionRef *openapi3.SchemaRef) {
+	base, baseNullable := unwrap(d.base, resolve(d.base, baseRef))
+	revision, revisionNullable := unwrap(d.revision, resolve(d.revision, revisionRef))
+
+	switch {
+	case (base == nil) && (revision == nil):
+		return
+	case (base == nil) || (revision == nil):
+		d.add(true, location, "schema changed")
+		return
+	}
+
+	pair := schemaPair{base: base, revision: revision, dir: dir}
+	if d.visiting == nil {
+		d.visiting = map[schemaPair]bool{}
+	}
+	if d.visiting[pair] {
+		return
+	}
+	d.visiting[pair] = true
+	defer delete(d.visiting, pair)
+
+	baseTypes, revisionTypes := types(base), types(revision)
+	// no type accepts anything
+	if (len(baseTypes) > 0) && (strings.Join(baseTypes, ",") != strings.Join(revisionTypes, ",")) {
+		d.add(true, location, "type changed from %s to %s", typeName(baseTypes), typeName(revisionTypes))
+		return
+	}
+
+	if base.Format != revision.Format {
+		d.add(true, location, "format changed from %q to %q", base.Format, revision.Format)
+	}
+
+	d.compareNullable(location, dir, baseNullable, revisionNullable)
+	d.compareEnum(location, dir, base, revision)
+	d.compareLimits(location, dir, base, revision)
+	d.compareProperties(location, dir, base, revision)
+
+	if (base.Items != nil) && (revision.Items != nil) {
+		d.compareSchemas(location+"[]", dir, base.Items, revision.Items)
+	}
+
+	if (base.AdditionalProperties.Schema != nil) && (revision.AdditionalProperties.Schema != nil) {
+		d.compareSchemas(location+"{}", dir, base.AdditionalProperties.Schema, revision.AdditionalProperties.Schema)
+	}
+
+	d.compareVariants(location, dir, "oneOf", base.OneOf, revision.OneOf)
+	d.compareVariants(location, dir, "anyOf", base.AnyOf, revision.AnyOf)
+	d.compareVariants(location, dir, "allOf", base.AllOf, revision.AllOf)
+}
+
+func (d *differ) compareNullable(location string, dir direction, baseNullable bool, revisionNullable bool) {
+	switch {
+	case !baseNullable && revisionNullable:
+		d.add(dir == inResponse, location, "is now nullable")
+	case baseNullable && !revisionNullable:
+		d.add(dir == inRequest, location, "is no longer nullable")
+	}
+}
+
+// compareEnum reports the values the clients could send but are now rejected,
+// and the values the clients could not expect
+func (d *differ) compareEnum(location string, dir direction, base *openapi3.Schema, revision *openapi3.Schema) {
+	switch {
+	case (len(base.Enum) == 0) && (len(revision.Enum) == 0):
+		return
+
+	case len(base.Enum) == 0:
+		d.add(dir == inRequest, location, "values restricted to %s", strings.Join(enumValues(revision.Enum), ", "))
+		return
+
+	case len(revision.Enum) == 0:
+		d.add(dir == inResponse, location, "values no longer restricted")
+		return
+	}
+
+	baseValues, revisionValues := enumSet(base.Enum), enumSet(revision.Enum)
+
+	removed := []string{}
+	for _, value := range enumValues(base.Enum) {
+		if !revisionValues[value] {
+			removed = append(removed, value)
+		}
+	}
+
+	added := []string{}
+	for _, value := range enumValues(revision.Enum) {
+		if !baseValues[value] {
+			added = append(added, value)
+		}
+	}
+
+	if len(removed) > 0 {
+		d.add(dir == inRequest, location, "enum values removed: %s", strings.Join(removed, ", "))
+	}
+
+	if len(added) > 0 {
+		d.add(dir == inResponse, location, "enum values added: %s", strings.Join(added, ", "))
+	}
+}
+
+// compareLimits reports the tighter limits for the requests and the looser
+// ones for the responses
+func (d *differ) compareLimits(location string, dir direction, base *openapi3.Schema, revision *openapi3.Schema) {
+	limits := []struct {
+		name     string
+		max      bool
+		base     *float64
+		revision *float64
+	}{
+		{"minimum", false, base.Min, revision.Min},
+		{"maximum", true, base.Max, revision.Max},
+		{"minLength", false, uintValue(base.MinLength), uintValue(revision.MinLength)},
+		{"maxLength", true, uintPtr(base.MaxLength), uintPtr(revision.MaxLength)},
+		{"minItems", false, uintValue(base.MinItems), uintValue(revision.MinItems)},
+		{"maxItems", true, uintPtr(base.MaxItems), uintPtr(revision.MaxItems)},
+	}
+
+	for _, limit := range limits {
+		var tighter bool
+
+		switch {
+		case (limit.base == nil) && (limit.revision == nil):
+			continue
+		case limit.base == nil:
+			tighter = true
+		case limit.revision == nil:
+			tighter = false
+		case *limit.base == *limit.revision:
+			continue
+		case limit.max:
+			tighter = *limit.revision < *limit.base
+		default:
+			tighter = *limit.revision > *limit.base
+		}
+
+		if tighter {
+			d.add(dir == inRequest, location, "%s changed from %s to %s", limit.name, limitString(limit.base), limitString(limit.revision))
+		} else {
+			d.add(dir == inResponse, location, "%s changed from %s to %s", limit.name, limitString(limit.base), limitString(limit.revision))
+		}
+	}
+}
+
+func (d *differ) compareProperties(location string, dir direction, base *openapi3.Schema, revision *openapi3.Schema) {
+	baseRequired, revisionRequired := stringSet(base.Required), stringSet(revision.Required)
+
+	for _, name := range sortedKeys(base.Properties, revision.Properties) {
+		propertyLocation := location + "." + name
+		baseProperty, revisionProperty := base.Properties[name], revision.Properties[name]
+
+		switch {
+		case revisionProperty == nil:
+			d.add(true, propertyLocation, "property removed")
+
+		case baseProperty == nil:
+			if (dir == inRequest) && revisionRequired[name] {
+				d.add(true, propertyLocation, "required property added")
+			} else {
+				d.add(false, propertyLocation, "property added")
+			}
+
+		default:
+			switch {
+			case !baseRequired[name] && revisionRequired[name]:
+				d.add(dir == inRequest, propertyLocation, "property is now required")
+			case baseRequired[name] && !revisionRequired[name]:
+				d.add(dir == inResponse, propertyLocation, "property is no longer required")
+			}
+
+			d.compareSchemas(propertyLocation, dir, baseProperty, revisionProperty)
+		}
+	}
+}
+
+// compareVariants compares the schemas of oneOf, anyOf and allOf by position
+func (d *differ) compareVariants(location string, dir direction, kind string, base openapi3.SchemaRefs, revision openapi3.SchemaRefs) {
+	for i := 0; (i < len(base)) && (i < len(revision)); i++ {
+		d.compareSchemas(fmt.Sprintf("%s.%s[%d]", location, kind, i), dir, base[i], revision[i])
+	}
+
+	switch {
+	case len(revision) < len(base):
+		d.add(dir == inRequest, location, "%s variants removed", kind)
+	case len(revision) > len(base):
+		d.add(dir == inResponse, location, "%s variants added", kind)
+	}
+}
+
+// resolve returns the schema of ref, the references to the components are
+// looked up in doc when not loaded
+func resolve(doc *openapi3.T, ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref == nil {
+		return nil
+	}
+
+	if ref.Value != nil {
+		return ref.Value
+	}
+
+	name, found := strings.CutPrefix(ref.Ref, "#/components/schemas/")
+	if !found || (doc == nil) || (doc.Components == nil) {
+		return nil
+	}
+
+	component := doc.Components.Schemas[name]
+	if (component == nil) || (component.Ref == ref.Ref) {
+		return nil
+	}
+
+	return resolve(doc, component)
+}
+
+// unwrap returns the schema referenced by the single element allOf used to
+// annotate a reference (ex: deprecated, nullable), and whether it is nullable
+func unwrap(doc *openapi3.T, schema *openapi3.Schema) (*openapi3.Schema, bool) {
+	if schema == nil {
+		return nil, false
+	}
+
+	isNullable := nullable(schema)
+	seen := map[*openapi3.Schema]bool{}
+	for isWrapper(schema) && !seen[schema] {
+		seen[schema] = true
+
+		inner := resolve(doc, schema.AllOf[0])
+		if inner == nil {
+			break
+		}
+
+		schema = inner
+		isNullable = isNullable || nullable(schema)
+	}
+
+	return schema, isNullable
+}
+
+// isWrapper returns true if schema only holds annotations around its allOf
+func isWrapper(schema *openapi3.Schema) bool {
+	return (len(schema.AllOf) == 1) && (schema.Type == nil) && (schema.Format == "") &&
+		(len(schema.Properties) == 0) && (schema.Items == nil) && (schema.AdditionalProperties.Schema == nil) &&
+		(len(schema.OneOf) == 0) && (len(schema.AnyOf) == 0) && (len(schema.Enum) == 0)
+}
+
+// types returns the sorted types of schema, null excepted
+func types(schema *openapi3.Schema) []string {
+	ret := []string{}
+	if schema.Type == nil {
+		return ret
+	}
+
+	for _, typ := range schema.Type.Slice() {
+		if typ != openapi3.TypeNull {
+			ret = append(ret, typ)
+		}
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+func typeName(types []string) string {
+	if len(types) == 0 {
+		return "any"
+	}
+
+	return strings.Join(types, "|")
+}
+
+func nullable(schema *openapi3.Schema) bool {
+	return schema.Nullable || ((schema.Type != nil) && schema.Type.Includes(openapi3.TypeNull))
+}
+
+func enumValues(enum []interface{}) []string {
+	ret := make([]string, 0, len(enum))
+	for _, value := range enum {
+		ret = append(ret, fmt.Sprint(value))
+	}
+
+	return ret
+}
+
+func enumSet(enum []interface{}) map[string]bool {
+	return stringSet(enumValues(enum))
+}
+
+func stringSet(values []string) map[string]bool {
+	ret := make(map[string]bool, len(values))
+	for _, value := range values {
+		ret[value] = true
+	}
+
+	return ret
+}
+
+// the limits set to 0 are not limits
+func uintValue(value uint64) *float64 {
+	if value == 0 {
+		return nil
+	}
+
+	ret := float64(value)
+	return &ret
+}
+
+func uintPtr(value *uint64) *float64 {
+	if value == nil {
+		return nil
+	}
+
+	ret := float64(*value)
+	return &ret
+}
+
+func limitString(value *float64) string {
+	if value == nil {
+		return "none"
+	}
+
+	return fmt.Sprint(*value)
+}
@@ -3,11 +3,11 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/schmurfy/chipi/response"
+	"github.com/schmurfy/chipi/wrapper"
 )
 
 type RequestWithFields struct {
@@ -42,8 +42,6 @@ func (r *CreateBikeRequest) DecodeBody(body io.ReadCloser, target interface{}, o
 		return err
 	}
 
-	fmt.Printf("%x %x\n", r, obj)
-
 	r.Fields = []string{r.Body.Id}
 
 	return nil
@@ -52,6 +50,6 @@ func (r *CreateBikeRequest) DecodeBody(body io.ReadCloser, target interface{}, o
 func (r *CreateBikeRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
 	w.Header().Set("Content-Type", "application/json")
 
-	fmt.Printf("req: %+v\n", r)
+	wrapper.LoggerFromContext(ctx).Info("creating bike", "id", r.Body.Id, "fields", r.Fields)
 	return json.NewEncoder(w).Encode(r)
 }
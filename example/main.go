@@ -1,9 +1,9 @@
 package main
 
 import (
-	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 
@@ -64,6 +64,10 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	api.SetLogger(logger)
+
 	router.Use(cors.AllowAll().Handler)
 
 	router.Get("/doc.json", api.ServeSchema)
@@ -134,7 +138,7 @@ func main() {
 		panic(err)
 	}
 
-	fmt.Printf("Started on 127.0.0.1:2121\n")
+	logger.Info("started", "address", "127.0.0.1:2121")
 
 	err = http.ListenAndServe(":2121", router)
 	if err != nil {
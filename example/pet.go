@@ -3,15 +3,23 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/schmurfy/chipi/response"
+	"github.com/schmurfy/chipi/wrapper"
 )
 
+// @description
+// A pet, owned by a user
 type Pet struct {
-	Id           int32  `json:"id"`
+	Id int32 `json:"id"`
+
+	// @description
+	// the name given by its owner
+	//
+	// @example
+	// Rex
 	Name         string `json:"name"`
 	Count        *int   `json:"count"`
 	User         *User  `json:"user" chipi:"nullable,deprecated"`
@@ -65,7 +73,7 @@ type GetPetRequest struct {
 	}
 
 	Header struct {
-		ApiKey string
+		ApiKey string `chipi:"sensitive"`
 	}
 
 	// @description
@@ -81,8 +89,10 @@ func (r *GetPetRequest) Handle(ctx context.Context, w http.ResponseWriter) error
 		Count: r.Query.Count,
 	})
 
-	fmt.Printf("names: %+v\n", r.Query.Names)
-	fmt.Printf("location: %+v\n", r.Query.Location)
+	wrapper.LoggerFromContext(ctx).Info("pet requested",
+		"names", r.Query.Names,
+		"location", r.Query.Location,
+	)
 
 	return err
 }
@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/schmurfy/chipi/request"
 	"github.com/schmurfy/chipi/response"
+	"github.com/schmurfy/chipi/wrapper"
 )
 
 type User struct {
@@ -56,7 +58,7 @@ func (r *GetUserRequest) Handle(ctx context.Context, w http.ResponseWriter) erro
 // upload user's resume
 type UploadResumeRequest struct {
 	response.ErrorEncoder
-	request.JsonBodyDecoder
+	request.MultipartBodyDecoder
 
 	Path struct {
 		Name string `example:"john"`
@@ -64,12 +66,18 @@ type UploadResumeRequest struct {
 	Query struct{}
 
 	Body struct {
-		File1 []byte `json:"file1"`
-		File2 []byte
+		File1 []byte                `json:"file1" max-size:"1MB"`
+		File2 *multipart.FileHeader `content-type:"application/pdf"`
 	} `content-type:"multipart/form-data"`
 }
 
 func (r *UploadResumeRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	if r.Body.File2 != nil {
+		wrapper.LoggerFromContext(ctx).Info("resume received",
+			"filename", r.Body.File2.Filename,
+			"size", r.Body.File2.Size,
+		)
+	}
 	return nil
 }
 
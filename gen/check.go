@@ -0,0 +1,98 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// why a generated file is not up to date
+const (
+	StaleMissing  = "missing"
+	StaleOutdated = "outdated"
+	StaleOrphaned = "orphaned"
+)
+
+// StaleFile is a generated file not matching its source
+type StaleFile struct {
+	Path   string
+	Reason string
+}
+
+func (f StaleFile) String() string {
+	switch f.Reason {
+	case StaleMissing:
+		return f.Path + ": missing, run chipi-gen"
+	case StaleOrphaned:
+		return f.Path + ": orphaned, its source has no annotations (or was removed), delete it"
+	default:
+		return f.Path + ": outdated, run chipi-gen"
+	}
+}
+
+// CheckDir generates the files of the folder in memory and returns the generated
+// files which are missing, differ from the files on disk or have no source anymore.
+func CheckDir(path string) ([]StaleFile, error) {
+	ret := []StaleFile{}
+	expected := map[string]bool{}
+
+	err := walkSources(path, func(pathWithoutExt string) error {
+		generatedPath := pathWithoutExt + generatedSuffix
+
+		data, err := GenerateFile(pathWithoutExt + ".go")
+		if err != nil {
+			return err
+		}
+
+		current, err := os.ReadFile(generatedPath)
+		exists := true
+		if errors.Is(err, fs.ErrNotExist) {
+			exists = false
+		} else if err != nil {
+			return err
+		}
+
+		switch {
+		case (len(data) == 0) && exists:
+			ret = append(ret, StaleFile{Path: generatedPath, Reason: StaleOrphaned})
+		case len(data) == 0:
+			// nothing to generate
+		case !exists:
+			ret = append(ret, StaleFile{Path: generatedPath, Reason: StaleMissing})
+		case !bytes.Equal(data, current):
+			ret = append(ret, StaleFile{Path: generatedPath, Reason: StaleOutdated})
+		}
+
+		expected[generatedPath] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the generated files of removed sources
+	err = filepath.WalkDir(path, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(path, generatedSuffix) && !expected[path] {
+			ret = append(ret, StaleFile{Path: path, Reason: StaleOrphaned})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+
+	return ret, nil
+}
@@ -0,0 +1,92 @@
+package gen
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("CheckDir", func() {
+		var dir string
+		var source string
+
+		g.BeforeEach(func() {
+			dir = t.TempDir()
+
+			data, err := os.ReadFile("../internal/testdata/monster/monster.go")
+			require.NoError(g, err)
+
+			source = filepath.Join(dir, "monster.go")
+			require.NoError(g, os.WriteFile(source, data, 0o644))
+		})
+
+		generated := func(name string) string {
+			return filepath.Join(dir, name+".generated.go")
+		}
+
+		g.It("should report the missing files", func() {
+			stale, err := CheckDir(dir)
+			require.NoError(g, err)
+			assert.Equal(g, []StaleFile{{Path: generated("monster"), Reason: StaleMissing}}, stale)
+		})
+
+		g.It("should accept the files up to date", func() {
+			require.NoError(g, InspectDir(dir, false))
+
+			stale, err := CheckDir(dir)
+			require.NoError(g, err)
+			assert.Empty(g, stale)
+		})
+
+		g.It("should write gofmt-clean files", func() {
+			require.NoError(g, InspectDir(dir, false))
+
+			data, err := os.ReadFile(generated("monster"))
+			require.NoError(g, err)
+
+			formatted, err := format.Source(data)
+			require.NoError(g, err)
+			assert.Equal(g, string(formatted), string(data))
+		})
+
+		g.It("should report the outdated files", func() {
+			require.NoError(g, InspectDir(dir, false))
+
+			data, err := os.ReadFile(source)
+			require.NoError(g, err)
+			data = []byte(strings.Replace(string(data), "This may be important", "This is important", 1))
+			require.NoError(g, os.WriteFile(source, data, 0o644))
+
+			stale, err := CheckDir(dir)
+			require.NoError(g, err)
+			assert.Equal(g, []StaleFile{{Path: generated("monster"), Reason: StaleOutdated}}, stale)
+		})
+
+		g.It("should report the orphaned files", func() {
+			require.NoError(g, InspectDir(dir, false))
+
+			// the source was removed
+			require.NoError(g, os.WriteFile(generated("removed"), []byte("package monster\n"), 0o644))
+
+			// the source has no annotations anymore
+			require.NoError(g, os.WriteFile(filepath.Join(dir, "plain.go"), []byte("package monster\n\ntype Plain struct{}\n"), 0o644))
+			require.NoError(g, os.WriteFile(generated("plain"), []byte("package monster\n"), 0o644))
+
+			stale, err := CheckDir(dir)
+			require.NoError(g, err)
+			assert.Equal(g, []StaleFile{
+				{Path: generated("plain"), Reason: StaleOrphaned},
+				{Path: generated("removed"), Reason: StaleOrphaned},
+			}, stale)
+		})
+	})
+}
@@ -0,0 +1,216 @@
+package gen
+
+import (
+	"go/token"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/dave/dst"
+)
+
+var (
+	enumBaseTypes = []string{
+		"string",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64",
+	}
+)
+
+type enumValue struct {
+	Name        string
+	Description string
+}
+
+func (ev enumValue) HasDescription() bool {
+	return ev.Description != ""
+}
+
+type enumType struct {
+	Type   string
+	Values []enumValue
+}
+
+var enumTemplate = template.Must(template.New("enum_template").Parse(`
+	{{ $sep := .StrSep }}
+	{{ with .Enum }}
+	func ({{ .Type }}) CHIPI_Enum() shared.Enum {
+		return shared.Enum{
+		{{ range .Values }}
+			{
+				Title: "{{ .Name }}",
+				Value: {{ .Name }},
+				{{ if .HasDescription }}
+					Description: strings.ReplaceAll({{ $sep }}{{ .Description }}{{ $sep }}, gen.RepBackticks, gen.Backticks),
+				{{ end }}
+			},
+		{{ end }}
+		}
+	}
+	{{ end }}
+`))
+
+// types declared in the files which can be used as enums
+// (ex: type Status string)
+func findEnumTypes(files ...*dst.File) map[string]bool {
+	ret := map[string]bool{}
+
+	for _, f := range files {
+		for _, node := range f.Decls {
+			if decl, ok := node.(*dst.GenDecl); ok && (decl.Tok == token.TYPE) {
+				for _, spec := range decl.Specs {
+					if tt, ok := spec.(*dst.TypeSpec); ok && !tt.Assign && (tt.TypeParams == nil) {
+						if ident, ok := tt.Type.(*dst.Ident); ok && isEnumBaseType(ident.Name) {
+							ret[tt.Name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return ret
+}
+
+func isEnumBaseType(name string) bool {
+	for _, s := range enumBaseTypes {
+		if s == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inspectEnums finds the constants declared in f with a type from the same
+// package (f or pkgFiles), they are returned in declaration order. Only the const ( ... ) blocks declaring
+// two values or more of a type are enums, a lone typed constant is not
+// (ex: const DefaultSize Size = 1024).
+func inspectEnums(f *dst.File, pkgFiles ...*dst.File) ([]*enumType, error) {
+	enumTypes := findEnumTypes(append([]*dst.File{f}, pkgFiles...)...)
+	ret := []*enumType{}
+	byType := map[string]*enumType{}
+
+	for _, node := range f.Decls {
+		decl, ok := node.(*dst.GenDecl)
+		if !ok || (decl.Tok != token.CONST) || !decl.Lparen {
+			continue
+		}
+
+		// the values of the block, by type
+		blockTypes := []string{}
+		blockValues := map[string][]enumValue{}
+
+		// the type is implicitly repeated when a spec has no value
+		// (ex: iota)
+		currentType := ""
+
+		for _, spec := range decl.Specs {
+			vs, ok := spec.(*dst.ValueSpec)
+			if !ok {
+				continue
+			}
+
+			switch {
+			case vs.Type != nil:
+				currentType = ""
+				if ident, ok := vs.Type.(*dst.Ident); ok {
+					currentType = ident.Name
+				}
+
+			case len(vs.Values) > 0:
+				currentType = ""
+				// ex: StatusA = Status("a")
+				if call, ok := vs.Values[0].(*dst.CallExpr); ok {
+					if ident, ok := call.Fun.(*dst.Ident); ok {
+						currentType = ident.Name
+					}
+				}
+			}
+
+			if !enumTypes[currentType] {
+				continue
+			}
+
+			description, err := constDescription(vs)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, found := blockValues[currentType]; !found {
+				blockTypes = append(blockTypes, currentType)
+			}
+
+			for _, name := range vs.Names {
+				if name.Name == "_" {
+					continue
+				}
+
+				blockValues[currentType] = append(blockValues[currentType], enumValue{
+					Name:        name.Name,
+					Description: description,
+				})
+			}
+		}
+
+		for _, typeName := range blockTypes {
+			values := blockValues[typeName]
+			if len(values) < 2 {
+				continue
+			}
+
+			et, found := byType[typeName]
+			if !found {
+				et = &enumType{Type: typeName}
+				byType[typeName] = et
+				ret = append(ret, et)
+			}
+
+			et.Values = append(et.Values, values...)
+		}
+	}
+
+	return ret, nil
+}
+
+// the description can either be a simple comment or use @description
+func constDescription(vs *dst.ValueSpec) (string, error) {
+	lines := vs.Decorations().Start
+	if len(lines) == 0 {
+		lines = vs.Decorations().End
+	}
+
+	commentData, err := parseComment(lines)
+	if err != nil {
+		return "", err
+	}
+
+	description, found := commentData["description"]
+	if !found {
+		description = commentData[""]
+	}
+
+	return strings.ReplaceAll(description, "`", repBackticks), nil
+}
+
+// GenerateEnums generates a CHIPI_Enum method for each type used by typed constants,
+// the types can be declared in the other files of the package (pkgFiles)
+func GenerateEnums(w io.Writer, f *dst.File, pkgName string, pkgFiles ...*dst.File) error {
+	enums, err := inspectEnums(f, pkgFiles...)
+	if err != nil {
+		return err
+	}
+
+	for _, et := range enums {
+		err := enumTemplate.Execute(w, map[string]interface{}{
+			"Enum":   et,
+			"StrSep": "`",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
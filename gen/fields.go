@@ -57,6 +57,8 @@ var paramTemplate = template.Must(template.New("param_template").Parse(`
 `))
 
 func GenerateFieldAnnotations(w io.Writer, f *dst.File, pkgName string) error {
+	// in declaration order, the generated files must not change between runs
+	keys := []string{}
 	group := map[string][]commentedField{}
 
 	err := inspectStructures(f, func(parentStructName string, sectionName string, fieldName string, data map[string]string) error {
@@ -66,6 +68,7 @@ func GenerateFieldAnnotations(w io.Writer, f *dst.File, pkgName string) error {
 
 		key := fmt.Sprintf("%s::%s", parentStructName, sectionName)
 		if _, exists := group[key]; !exists {
+			keys = append(keys, key)
 			group[key] = []commentedField{}
 		}
 
@@ -102,15 +105,13 @@ func GenerateFieldAnnotations(w io.Writer, f *dst.File, pkgName string) error {
 		return err
 	}
 
-	if len(group) > 0 {
-		for _, cfs := range group {
-			err := paramTemplate.Execute(w, map[string]interface{}{
-				"Fields": cfs,
-				"StrSep": "`",
-			})
-			if err != nil {
-				return err
-			}
+	for _, key := range keys {
+		err := paramTemplate.Execute(w, map[string]interface{}{
+			"Fields": group[key],
+			"StrSep": "`",
+		})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -3,12 +3,14 @@ package gen
 import (
 	"bytes"
 	"fmt"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/dave/dst"
 	"github.com/dave/dst/decorator"
 )
 
@@ -22,6 +24,7 @@ import (
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/schmurfy/chipi/gen"
+	"github.com/schmurfy/chipi/shared"
 )
 
 
@@ -29,6 +32,8 @@ var (
 	// useless but required to not get a ù$* error about unused imports
 	_ = strings.ToLower("")
 	_ = gen.RepBackticks
+	_ = openapi3.NewSchema
+	_ = shared.Enum(nil)
 )
 `
 
@@ -36,12 +41,27 @@ func FilterIncludeAll(fi os.FileInfo) bool {
 	return true
 }
 
+// the suffix of the generated files
+const generatedSuffix = ".generated.go"
+
 func InspectDir(path string, noWrite bool) error {
+	return walkSources(path, func(pathWithoutExt string) error {
+		return generateDataForFile(pathWithoutExt, noWrite)
+	})
+}
+
+// walkSources calls fn for every go file of the folder (and its sub folders),
+// without its extension
+func walkSources(path string, fn func(pathWithoutExt string) error) error {
 	return filepath.WalkDir(path, func(path string, entry os.DirEntry, err error) error {
-		if filepath.Ext(path) == ".go" {
+		if err != nil {
+			return err
+		}
+
+		if (filepath.Ext(path) == ".go") && !strings.HasSuffix(path, generatedSuffix) {
 			pathWithoutExt := strings.TrimSuffix(path, filepath.Ext(path))
 
-			err := generateDataForFile(pathWithoutExt, noWrite)
+			err := fn(pathWithoutExt)
 			if err != nil {
 				return err
 			}
@@ -52,57 +72,122 @@ func InspectDir(path string, noWrite bool) error {
 }
 
 func generateDataForFile(path string, noWrite bool) error {
+	data, err := GenerateFile(path + ".go")
+	if err != nil {
+		return err
+	}
+
+	generatedPath := path + generatedSuffix
+
+	// write file (or log)
+	if len(data) > 0 {
+		if noWrite {
+			fmt.Printf("Would have written to %s\n", generatedPath)
+		} else {
+			err = os.WriteFile(generatedPath, data, 0666)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GenerateFile returns the formatted content of the generated file for the go
+// file at path, nothing is returned when the file has no annotations.
+func GenerateFile(path string) ([]byte, error) {
 	fset := token.NewFileSet()
 
-	data, err := os.ReadFile(path + ".go")
+	data, err := os.ReadFile(path)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	file, err := decorator.ParseFile(fset, path, data, parser.ParseComments)
+	file, err := decorator.ParseFile(fset, strings.TrimSuffix(path, filepath.Ext(path)), data, parser.ParseComments)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	generatedPath := path + ".generated.go"
-
 	buffer := bytes.NewBufferString("")
 
 	pkgName := file.Name.String()
 
 	err = GenerateFieldAnnotations(buffer, file, pkgName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = GenerateOperationAnnotations(buffer, file, pkgName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// write file (or log)
-	if len(buffer.String()) > 0 {
-		if noWrite {
-			fmt.Printf("Would have written to %s\n", generatedPath)
-		} else {
-			w, err := os.OpenFile(generatedPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-			if err != nil {
-				return err
-			}
+	err = GenerateSchemaAnnotations(buffer, file, pkgName)
+	if err != nil {
+		return nil, err
+	}
 
-			header := fmt.Sprintf(fileHeader, pkgName)
-			_, err = w.WriteString(header)
-			if err != nil {
-				return err
-			}
+	pkgFiles, err := packageFiles(fset, path, pkgName)
+	if err != nil {
+		return nil, err
+	}
 
-			_, err = w.WriteString(buffer.String())
-			if err != nil {
-				return err
-			}
+	err = GenerateEnums(buffer, file, pkgName, pkgFiles...)
+	if err != nil {
+		return nil, err
+	}
 
+	if buffer.Len() == 0 {
+		return nil, nil
+	}
+
+	data = append([]byte(fmt.Sprintf(fileHeader, pkgName)), buffer.Bytes()...)
+
+	// the written and checked files are gofmt-clean
+	formatted, err := format.Source(data)
+	if err != nil {
+		return nil, fmt.Errorf("formatting the generated code of %s: %w", path, err)
+	}
+
+	return formatted, nil
+}
+
+// packageFiles parses the other files of the package of the go file at path,
+// the tests and generated files excepted
+func packageFiles(fset *token.FileSet, path string, pkgName string) ([]*dst.File, error) {
+	dir := filepath.Dir(path)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []*dst.File{}
+	for _, entry := range entries {
+		name := entry.Name()
+		filePath := filepath.Join(dir, name)
+
+		if entry.IsDir() || (filepath.Ext(name) != ".go") || (filePath == filepath.Clean(path)) ||
+			strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, generatedSuffix) {
+			continue
+		}
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := decorator.ParseFile(fset, filePath, data, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		// other packages can live in the same folder (ex: package main with a build tag)
+		if file.Name.String() == pkgName {
+			ret = append(ret, file)
 		}
 	}
 
-	return nil
+	return ret, nil
 }
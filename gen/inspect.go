@@ -1,6 +1,8 @@
 package gen
 
 import (
+	"go/types"
+
 	"github.com/dave/dst"
 )
 
@@ -16,8 +18,8 @@ func inspectStructures(f *dst.File, cb inspectFunc) error {
 			for _, spec := range decl.Specs {
 				if tt, ok := spec.(*dst.TypeSpec); ok {
 
-					// we found a struct
-					if st, ok := tt.Type.(*dst.StructType); ok {
+					// we found a request struct
+					if st, ok := tt.Type.(*dst.StructType); ok && isRequestStructure(st) {
 						err := inspectRequestStructure(decl, tt, st, cb)
 						if err != nil {
 							return err
@@ -32,6 +34,103 @@ func inspectStructures(f *dst.File, cb inspectFunc) error {
 	return nil
 }
 
+// request structures are the ones with a Path, Query, Header or Body section,
+// a section has a structure type (ex: Body string is a model field)
+func isRequestStructure(st *dst.StructType) bool {
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			switch name.Name {
+			case "Path", "Query", "Header", "Body":
+				if isSectionType(field.Type) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func isSectionType(expr dst.Expr) bool {
+	switch t := expr.(type) {
+	case *dst.StructType, *dst.SelectorExpr:
+		return true
+	case *dst.StarExpr:
+		return isSectionType(t.X)
+	case *dst.Ident:
+		// not a builtin type (ex: string)
+		return types.Universe.Lookup(t.Name) == nil
+	}
+
+	return false
+}
+
+type inspectModelFunc func(structName string, fieldName string, data map[string]string) error
+
+// inspect every structure which is not a request structure and invoke
+// the callback for the structure and each of its fields whenever a
+// comment is found
+func inspectModels(f *dst.File, cb inspectModelFunc) error {
+	for _, node := range f.Decls {
+		if decl, ok := node.(*dst.GenDecl); ok {
+			for _, spec := range decl.Specs {
+				if tt, ok := spec.(*dst.TypeSpec); ok {
+					if st, ok := tt.Type.(*dst.StructType); ok && !isRequestStructure(st) {
+						err := inspectModelStructure(decl, tt, st, cb)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func inspectModelStructure(decl *dst.GenDecl, typeSpec *dst.TypeSpec, st *dst.StructType, cb inspectModelFunc) error {
+	// comments are attached to the declaration unless the type
+	// is part of a type ( ... ) block
+	structDecorations := typeSpec.Decorations().Start
+	if len(decl.Specs) == 1 {
+		structDecorations = decl.Decorations().Start
+	}
+
+	if len(structDecorations) > 0 {
+		commentData, err := parseComment(structDecorations)
+		if err != nil {
+			return err
+		}
+
+		err = cb(typeSpec.Name.String(), "", commentData)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, field := range st.Fields.List {
+		startDecoration := field.Decorations().Start
+		if len(startDecoration) == 0 {
+			continue
+		}
+
+		commentData, err := parseComment(startDecoration)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range field.Names {
+			err = cb(typeSpec.Name.String(), name.String(), commentData)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func isValidField(name string) bool {
 	for _, s := range validFields {
 		if s == name {
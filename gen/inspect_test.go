@@ -5,6 +5,8 @@ import (
 	"go/parser"
 	"go/token"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/dave/dst"
@@ -66,6 +68,7 @@ func TestGenerator(t *testing.T) {
 					{"GetMonsterRequest", "Header", "ApiKey", data("The _ApiKey_ is required to\ncheck for authorization")},
 					{"GetMonsterRequest", "Header", "Something", data("This may be important")},
 					{"GetMonsterRequest", "Response", "", data("what is returned")},
+					{"SearchMonstersRequest", "Query", "Name", data("the searched name")},
 				}
 
 				err := inspectStructures(f, func(parentStructName string, sectionName string, fieldName string, data map[string]string) error {
@@ -83,11 +86,128 @@ func TestGenerator(t *testing.T) {
 				})
 
 				require.NoError(g, err)
-				assert.Equal(g, 7, pos)
+				assert.Equal(g, 8, pos)
 
 			})
 		})
 
+		g.Describe("InspectModels", func() {
+			g.It("should invoke callbacks for documented models and fields", func() {
+				pos := 0
+				expected := []struct {
+					structName string
+					field      string
+					data       map[string]string
+				}{
+					{"Monster", "", data("A scary monster")},
+					{"Monster", "Id", dataex("unique identifier", "42")},
+					{"Monster", "Name", map[string]string{"deprecated": ""}},
+					{"QueryResponse", "MonstersCount", map[string]string{"": "Monsters []Monster"}},
+					{"MonsterNote", "", map[string]string{"see QueryResponse": ""}},
+					{"MonsterNote", "Body", map[string]string{"todo": "use a rich text"}},
+				}
+
+				err := inspectModels(f, func(structName string, fieldName string, data map[string]string) error {
+					if pos >= len(expected) {
+						g.Failf("expected too short (index: %d)", pos)
+					}
+					dd := expected[pos]
+					assert.Equal(g, dd.structName, structName)
+					assert.Equal(g, dd.field, fieldName)
+					assert.Equal(g, dd.data, data)
+
+					pos++
+					return nil
+				})
+
+				require.NoError(g, err)
+				assert.Equal(g, 6, pos)
+			})
+
+			g.It("should generate schema annotations", func() {
+				buffer := bytes.NewBufferString("")
+				err := GenerateSchemaAnnotations(buffer, f, "monster")
+				require.NoError(g, err)
+
+				assert.Contains(g, buffer.String(), "func (*Monster) CHIPI_Schema_Annotations(field string) *openapi3.Schema")
+				assert.Contains(g, buffer.String(), `case "Id":`)
+				assert.NotContains(g, buffer.String(), "GetMonsterRequest")
+				assert.NotContains(g, buffer.String(), "SearchMonstersRequest")
+			})
+
+			g.It("should ignore the unknown properties of the models", func() {
+				buffer := bytes.NewBufferString("")
+				err := GenerateSchemaAnnotations(buffer, f, "monster")
+				require.NoError(g, err)
+
+				assert.NotContains(g, buffer.String(), "MonsterNote")
+			})
+		})
+
+		g.Describe("InspectEnums", func() {
+			g.It("should find typed constants", func() {
+				enums, err := inspectEnums(f)
+				require.NoError(g, err)
+
+				require.Len(g, enums, 2)
+
+				assert.Equal(g, &enumType{
+					Type: "MonsterKind",
+					Values: []enumValue{
+						{Name: "KindBed", Description: "lives under your bed"},
+						{Name: "KindCloset", Description: "lives in your closet"},
+						{Name: "KindGarden", Description: "lives in the garden"},
+					},
+				}, enums[0])
+
+				assert.Equal(g, &enumType{
+					Type: "DangerLevel",
+					Values: []enumValue{
+						{Name: "DangerLow"},
+						{Name: "DangerHigh"},
+						{Name: "DangerExtreme"},
+					},
+				}, enums[1])
+			})
+
+			g.It("should ignore the lone typed constants", func() {
+				enums, err := inspectEnums(f)
+				require.NoError(g, err)
+
+				for _, et := range enums {
+					assert.NotEqual(g, "MonsterSize", et.Type)
+				}
+			})
+
+			g.It("should find the types declared in other files of the package", func() {
+				dir := t.TempDir()
+				require.NoError(g, os.WriteFile(filepath.Join(dir, "types.go"), []byte("package colors\n\ntype Color string\n"), 0o644))
+				require.NoError(g, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package other\n\ntype Color int\n"), 0o644))
+
+				source := filepath.Join(dir, "consts.go")
+				require.NoError(g, os.WriteFile(source, []byte(`package colors
+
+const (
+	Red   Color = "red"
+	Green Color = "green"
+)
+`), 0o644))
+
+				data, err := GenerateFile(source)
+				require.NoError(g, err)
+				assert.Contains(g, string(data), "func (Color) CHIPI_Enum() shared.Enum")
+			})
+
+			g.It("should generate enum methods", func() {
+				buffer := bytes.NewBufferString("")
+				err := GenerateEnums(buffer, f, "monster")
+				require.NoError(g, err)
+
+				assert.Contains(g, buffer.String(), "func (MonsterKind) CHIPI_Enum() shared.Enum")
+				assert.Contains(g, buffer.String(), "func (DangerLevel) CHIPI_Enum() shared.Enum")
+			})
+		})
+
 		g.Describe("GenerateAnnotations", func() {
 			g.It("should generate annotations", func() {
 				buffer := bytes.NewBufferString("")
@@ -96,6 +216,27 @@ func TestGenerator(t *testing.T) {
 
 				// TODO: test the content
 			})
+
+			g.It("should generate the sections in declaration order", func() {
+				generate := func() string {
+					buffer := bytes.NewBufferString("")
+					require.NoError(g, GenerateFieldAnnotations(buffer, f, "monster"))
+					require.NoError(g, GenerateOperationAnnotations(buffer, f, "monster"))
+					return buffer.String()
+				}
+
+				output := generate()
+
+				path := strings.Index(output, "CHIPI_Path_Annotations")
+				query := strings.Index(output, "CHIPI_Query_Annotations")
+				header := strings.Index(output, "CHIPI_Header_Annotations")
+				require.True(g, (path >= 0) && (query >= 0) && (header >= 0))
+				assert.True(g, (path < query) && (query < header))
+
+				for i := 0; i < 10; i++ {
+					require.Equal(g, output, generate())
+				}
+			})
 		})
 
 	})
@@ -71,6 +71,8 @@ var operationTemplate = template.Must(template.New("operation_template").Parse(`
 `))
 
 func GenerateOperationAnnotations(w io.Writer, f *dst.File, pkgName string) error {
+	// in declaration order, the generated files must not change between runs
+	keys := []string{}
 	operations := map[string][]commentedOperation{}
 
 	err := inspectStructures(f, func(parentStructName string, sectionName string, fieldName string, data map[string]string) error {
@@ -80,6 +82,7 @@ func GenerateOperationAnnotations(w io.Writer, f *dst.File, pkgName string) erro
 
 		key := parentStructName
 		if _, exists := operations[key]; !exists {
+			keys = append(keys, key)
 			operations[key] = []commentedOperation{}
 		}
 
@@ -113,15 +116,13 @@ func GenerateOperationAnnotations(w io.Writer, f *dst.File, pkgName string) erro
 		return err
 	}
 
-	if len(operations) > 0 {
-		for _, cfs := range operations {
-			err := operationTemplate.Execute(w, map[string]interface{}{
-				"Fields": cfs,
-				"StrSep": "`",
-			})
-			if err != nil {
-				return err
-			}
+	for _, key := range keys {
+		err := operationTemplate.Execute(w, map[string]interface{}{
+			"Fields": operations[key],
+			"StrSep": "`",
+		})
+		if err != nil {
+			return err
 		}
 	}
 
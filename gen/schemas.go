@@ -0,0 +1,115 @@
+package gen
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/dave/dst"
+)
+
+type commentedSchemaField struct {
+	Parent string
+	Field  string
+
+	Description string
+	Example     string
+	Deprecated  bool
+}
+
+func (cf commentedSchemaField) HasDescription() bool {
+	return cf.Description != ""
+}
+
+func (cf commentedSchemaField) HasExample() bool {
+	return cf.Example != ""
+}
+
+var schemaTemplate = template.Must(template.New("schema_template").Parse(`
+	{{ $fields := .Fields }}
+	{{ $sep := .StrSep }}
+	{{ with $first := index .Fields 0 }}
+
+	{{ with $first }}
+	func (*{{.Parent}}) CHIPI_Schema_Annotations(field string) *openapi3.Schema {
+	{{end}}
+		switch field {
+		{{ range $fields }}
+		case "{{ .Field }}":
+			return &openapi3.Schema{
+				{{ if .HasDescription }}
+					Description: strings.ReplaceAll({{ $sep }}{{.Description}}{{ $sep }}, gen.RepBackticks, gen.Backticks),
+				{{ end }}
+
+				{{ if .HasExample }}
+					Example: {{ $sep }}{{ .Example }}{{ $sep }},
+				{{end}}
+
+				Deprecated: {{ .Deprecated }},
+			}
+		{{ end }}
+		}
+
+		return nil
+	}
+
+	{{ end }}
+`))
+
+// GenerateSchemaAnnotations generates a CHIPI_Schema_Annotations method for every
+// documented model structure, the field name is empty for the structure itself.
+func GenerateSchemaAnnotations(w io.Writer, f *dst.File, pkgName string) error {
+	structNames := []string{}
+	group := map[string][]commentedSchemaField{}
+
+	err := inspectModels(f, func(structName string, fieldName string, data map[string]string) error {
+		cf := commentedSchemaField{
+			Parent: structName,
+			Field:  fieldName,
+		}
+
+		// the models are not only documented for chipi, the other
+		// properties (ex: @see, @todo) and plain comments are ignored
+		found := false
+		for k, v := range data {
+			switch k {
+			case "description":
+				cf.Description = strings.ReplaceAll(v, "`", repBackticks)
+				found = true
+			case "example":
+				cf.Example = strings.ReplaceAll(v, "`", repBackticks)
+				found = true
+			case "deprecated":
+				cf.Deprecated = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil
+		}
+
+		if _, exists := group[structName]; !exists {
+			structNames = append(structNames, structName)
+		}
+
+		group[structName] = append(group[structName], cf)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, structName := range structNames {
+		err := schemaTemplate.Execute(w, map[string]interface{}{
+			"Fields": group[structName],
+			"StrSep": "`",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,7 +1,15 @@
 package monster
 
+// @description
+// A scary monster
 type Monster struct {
-	Id   int32  `json:"id"`
+	// @description
+	// unique identifier
+	// @example
+	// 42
+	Id int32 `json:"id"`
+
+	// @deprecated
 	Name string `json:"name"`
 }
 
@@ -52,3 +60,52 @@ type QueryResponse struct {
 	// Monsters []Monster
 	MonstersCount int
 }
+
+// @see QueryResponse
+type MonsterNote struct {
+	// @todo
+	// use a rich text
+	Body string
+}
+
+type SearchMonstersRequest struct {
+	Query struct {
+		// @description
+		// the searched name
+		Name string
+	}
+}
+
+type MonsterKind string
+
+const (
+	// @description
+	// lives under your bed
+	KindBed MonsterKind = "bed"
+
+	// lives in your closet
+	KindCloset MonsterKind = "closet"
+
+	KindGarden = MonsterKind("garden") // lives in the garden
+)
+
+type DangerLevel int
+
+const (
+	DangerLow DangerLevel = iota
+	DangerHigh
+	_
+	DangerExtreme
+)
+
+const notAnEnum = 42
+
+type MonsterSize int
+
+// a single typed constant is not an enum
+const DefaultSize MonsterSize = 3
+
+const (
+	MaxSize MonsterSize = 10
+	Timeout             = 5
+)
@@ -0,0 +1,299 @@
+// Package mock serves a specification without its implementation: the requests
+// are validated against the documented parameters and bodies and the responses
+// are built from the examples, or synthesized from the schemas.
+//
+//	handler, err := mock.NewHandler(spec)
+//	if err != nil {
+//		return err
+//	}
+//	http.ListenAndServe(":2121", handler)
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/response"
+	"github.com/schmurfy/chipi/shared"
+	"github.com/schmurfy/chipi/wrapper"
+)
+
+// StatusHeader selects the documented status returned by the mock,
+// the first documented success is returned without it
+const StatusHeader = "X-Mock-Status"
+
+// NewHandler returns a handler serving every operation of spec, the
+// references of spec must be resolved (see openapi3.Loader).
+func NewHandler(spec *openapi3.T) (http.Handler, error) {
+	if (spec == nil) || (spec.Paths == nil) {
+		return nil, errors.New("mock: a specification with paths is required")
+	}
+
+	router := chi.NewRouter()
+
+	for _, path := range spec.Paths.InMatchingOrder() {
+		pathItem := spec.Paths.Value(path)
+
+		for method, op := range pathItem.Operations() {
+			route := &routers.Route{
+				Spec:      spec,
+				Path:      path,
+				PathItem:  pathItem,
+				Method:    method,
+				Operation: op,
+			}
+
+			router.Method(method, path, &operation{route: route})
+		}
+	}
+
+	return router, nil
+}
+
+// operation answers the requests of one documented operation
+type operation struct {
+	route *routers.Route
+}
+
+func (o *operation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	errorStatus, parsingErrors := validateRequest(r, o.route)
+	if len(parsingErrors) > 0 {
+		wrapper.WriteParsingErrors(w, errorStatus, parsingErrors)
+		return
+	}
+
+	status, responseRef, err := selectResponse(r, o.route.Operation)
+	if err != nil {
+		wrapper.WriteParsingErrors(w, http.StatusBadRequest, map[string]string{
+			"request.header." + StatusHeader: err.Error(),
+		})
+		return
+	}
+
+	content := responseRef.Value.Content
+	if len(content) == 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	contentType, err := response.NegotiateContentType(r.Header.Get("Accept"),
+		shared.SortedContentTypes(content), "application/json")
+	if err != nil {
+		wrapper.WriteParsingErrors(w, http.StatusNotAcceptable, map[string]string{
+			"request.header.Accept": err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	_ = encode(w, contentType, mediaValue(content.Get(contentType)))
+}
+
+// selectResponse returns the response chosen with StatusHeader, or the
+// first documented success
+func selectResponse(r *http.Request, op *openapi3.Operation) (int, *openapi3.ResponseRef, error) {
+	if (op.Responses == nil) || (op.Responses.Len() == 0) {
+		return http.StatusNoContent, &openapi3.ResponseRef{Value: &openapi3.Response{}}, nil
+	}
+
+	if header := r.Header.Get(StatusHeader); header != "" {
+		status, err := strconv.Atoi(header)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid status %q", header)
+		}
+
+		responseRef := op.Responses.Status(status)
+		if (responseRef == nil) || (responseRef.Value == nil) {
+			return 0, nil, fmt.Errorf("status %d is not documented", status)
+		}
+
+		return status, responseRef, nil
+	}
+
+	statuses := []int{}
+	for code, responseRef := range op.Responses.Map() {
+		status, err := strconv.Atoi(code)
+		if (err != nil) || (responseRef.Value == nil) {
+			continue
+		}
+
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+
+	if len(statuses) == 0 {
+		return 0, nil, errors.New("no status is documented")
+	}
+
+	// the first success, or the first status when none is documented
+	status := statuses[0]
+	for _, s := range statuses {
+		if (s >= 200) && (s < 300) {
+			status = s
+			break
+		}
+	}
+
+	return status, op.Responses.Status(status), nil
+}
+
+// validateRequest checks the parameters and the body of r, the errors
+// are returned in the same format as the wrapper
+func validateRequest(r *http.Request, route *routers.Route) (int, map[string]string) {
+	op := route.Operation
+
+	// same status as the wrapper for the unknown bodies
+	if (op.RequestBody != nil) && (op.RequestBody.Value != nil) && (r.ContentLength != 0) {
+		contentType := r.Header.Get("Content-Type")
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+
+		if op.RequestBody.Value.Content.Get(mediaType) == nil {
+			return http.StatusUnsupportedMediaType, map[string]string{
+				"request.body": fmt.Sprintf("%s: %q, expected one of: %s", wrapper.ErrUnsupportedMediaType.Error(),
+					contentType, strings.Join(shared.SortedContentTypes(op.RequestBody.Value.Content), ", ")),
+			}
+		}
+	}
+
+	pathParams := map[string]string{}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, key := range rctx.URLParams.Keys {
+			pathParams[key] = rctx.URLParams.Values[i]
+		}
+	}
+
+	err := openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:         true,
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	})
+	if err == nil {
+		return 0, nil
+	}
+
+	ret := map[string]string{}
+	addValidationErrors(ret, err)
+
+	return http.StatusBadRequest, ret
+}
+
+func addValidationErrors(ret map[string]string, err error) {
+	// the errors of a parameter are also grouped in a MultiError
+	if multi, ok := err.(openapi3.MultiError); ok {
+		for _, e := range multi {
+			addValidationErrors(ret, e)
+		}
+		return
+	}
+
+	key := "request"
+
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) {
+		switch {
+		case requestErr.Parameter != nil:
+			key = fmt.Sprintf("request.%s.%s", requestErr.Parameter.In, requestErr.Parameter.Name)
+		case requestErr.RequestBody != nil:
+			key = "request.body"
+		}
+	}
+
+	if previous, found := ret[key]; found {
+		ret[key] = previous + ", " + err.Error()
+		return
+	}
+
+	ret[key] = err.Error()
+}
+
+// mediaValue returns the documented example or a value built from the schema
+func mediaValue(media *openapi3.MediaType) interface{} {
+	if media.Example != nil {
+		return media.Example
+	}
+
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if example := media.Examples[names[0]]; (example != nil) && (example.Value != nil) {
+			return example.Value.Value
+		}
+	}
+
+	return Synthesize(media.Schema)
+}
+
+// encode writes value as contentType, the streams (ndjson and sse) send one
+// item per line or event, strings are sent as is for the other types
+func encode(w io.Writer, contentType string, value interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	switch {
+	case (mediaType == "application/json") || strings.HasSuffix(mediaType, "+json"):
+		return json.NewEncoder(w).Encode(value)
+
+	case mediaType == "application/x-ndjson":
+		enc := json.NewEncoder(w)
+		for _, item := range items(value) {
+			err := enc.Encode(item)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case mediaType == "text/event-stream":
+		for _, item := range items(value) {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if s, ok := value.(string); ok {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(value)
+}
+
+// the streams are documented as arrays
+func items(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+
+	return []interface{}{value}
+}
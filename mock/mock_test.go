@@ -0,0 +1,197 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const _spec = `{
+  "openapi": "3.1.0",
+  "info": {"title": "pets", "version": "1"},
+  "paths": {
+    "/pets/{Id}": {
+      "get": {
+        "operationId": "getPetRequest",
+        "parameters": [
+          {"name": "Id", "in": "path", "required": true, "schema": {"type": "integer"}},
+          {"name": "Limit", "in": "query", "schema": {"type": "integer", "maximum": 10}}
+        ],
+        "responses": {
+          "200": {
+            "description": "the pet",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Pet"}},
+              "application/x-ndjson": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Pet"}}}
+            }
+          },
+          "404": {"description": "not found"}
+        }
+      }
+    },
+    "/pets": {
+      "post": {
+        "operationId": "createPetRequest",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "created",
+            "content": {
+              "application/json": {"example": {"id": 42, "name": "fido"}}
+            }
+          }
+        }
+      },
+      "delete": {
+        "operationId": "deletePetsRequest",
+        "responses": {
+          "204": {"description": "no data"}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Pet": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "id": {"type": "integer", "example": "12"},
+          "name": {"type": "string", "maxLength": 4},
+          "born": {"type": "string", "format": "date-time"},
+          "kind": {"type": "string", "enum": ["cat", "dog"]},
+          "secret": {"type": "string", "writeOnly": true},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "parent": {"$ref": "#/components/schemas/Pet"}
+        }
+      }
+    }
+  }
+}`
+
+func TestMock(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Handler", func() {
+		var handler http.Handler
+
+		g.Before(func() {
+			spec, err := openapi3.NewLoader().LoadFromData([]byte(_spec))
+			require.NoError(g, err)
+
+			handler, err = NewHandler(spec)
+			require.NoError(g, err)
+		})
+
+		call := func(r *http.Request) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		g.It("should synthesize the responses from the schemas", func() {
+			w := call(httptest.NewRequest("GET", "/pets/1", nil))
+			require.Equal(g, http.StatusOK, w.Code)
+			assert.Equal(g, "application/json", w.Header().Get("Content-Type"))
+
+			assert.JSONEq(g, `{
+				"id": 12,
+				"name": "stri",
+				"born": "2024-01-02T15:04:05Z",
+				"kind": "cat",
+				"tags": ["string"]
+			}`, w.Body.String())
+		})
+
+		g.It("should use the examples of the responses", func() {
+			r := httptest.NewRequest("POST", "/pets", strings.NewReader(`{"name": "fido"}`))
+			r.Header.Set("Content-Type", "application/json")
+
+			w := call(r)
+			require.Equal(g, http.StatusCreated, w.Code)
+			assert.JSONEq(g, `{"id": 42, "name": "fido"}`, w.Body.String())
+		})
+
+		g.It("should send the streams as one item per line", func() {
+			r := httptest.NewRequest("GET", "/pets/1", nil)
+			r.Header.Set("Accept", "application/x-ndjson")
+
+			w := call(r)
+			require.Equal(g, http.StatusOK, w.Code)
+
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			require.Len(g, lines, 1)
+
+			var pet map[string]interface{}
+			require.NoError(g, json.Unmarshal([]byte(lines[0]), &pet))
+			assert.Equal(g, "stri", pet["name"])
+		})
+
+		g.It("should return the requested status", func() {
+			r := httptest.NewRequest("GET", "/pets/1", nil)
+			r.Header.Set(StatusHeader, "404")
+
+			w := call(r)
+			assert.Equal(g, http.StatusNotFound, w.Code)
+			assert.Empty(g, w.Body.String())
+		})
+
+		g.It("should reject undocumented statuses", func() {
+			r := httptest.NewRequest("GET", "/pets/1", nil)
+			r.Header.Set(StatusHeader, "500")
+
+			w := call(r)
+			assert.Equal(g, http.StatusBadRequest, w.Code)
+			assert.Contains(g, w.Body.String(), "request.header.X-Mock-Status")
+		})
+
+		g.It("should return no content", func() {
+			w := call(httptest.NewRequest("DELETE", "/pets", nil))
+			assert.Equal(g, http.StatusNoContent, w.Code)
+		})
+
+		g.It("should validate the parameters", func() {
+			w := call(httptest.NewRequest("GET", "/pets/one?Limit=20", nil))
+			require.Equal(g, http.StatusBadRequest, w.Code)
+
+			errs := map[string]string{}
+			require.NoError(g, json.Unmarshal(w.Body.Bytes(), &errs))
+			assert.Contains(g, errs, "request.path.Id")
+			assert.Contains(g, errs, "request.query.Limit")
+		})
+
+		g.It("should validate the bodies", func() {
+			r := httptest.NewRequest("POST", "/pets", strings.NewReader(`{"id": 1}`))
+			r.Header.Set("Content-Type", "application/json")
+
+			w := call(r)
+			require.Equal(g, http.StatusBadRequest, w.Code)
+			assert.Contains(g, w.Body.String(), "request.body")
+		})
+
+		g.It("should reject unknown content types", func() {
+			r := httptest.NewRequest("POST", "/pets", strings.NewReader(`name=fido`))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			w := call(r)
+			assert.Equal(g, http.StatusUnsupportedMediaType, w.Code)
+		})
+
+		g.It("should reject unknown routes", func() {
+			w := call(httptest.NewRequest("GET", "/owners", nil))
+			assert.Equal(g, http.StatusNotFound, w.Code)
+		})
+	})
+}
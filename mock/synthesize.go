@@ -0,0 +1,262 @@
+package mock
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// values used for the strings with a known format
+var _formatExamples = map[string]string{
+	"date-time": "2024-01-02T15:04:05Z",
+	"date":      "2024-01-02",
+	"time":      "15:04:05",
+	"duration":  "1h30m0s",
+	"uuid":      "00000000-0000-0000-0000-000000000000",
+	"email":     "user@example.com",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"hostname":  "example.com",
+	"ipv4":      "127.0.0.1",
+	"ipv6":      "::1",
+	"ip":        "127.0.0.1",
+	"byte":      "",
+	"binary":    "",
+}
+
+// synthesizer builds example values from schemas, the recursive
+// references are cut to avoid infinite values
+type synthesizer struct {
+	visiting map[string]bool
+}
+
+func newSynthesizer() *synthesizer {
+	return &synthesizer{
+		visiting: map[string]bool{},
+	}
+}
+
+// Synthesize returns a value matching schema, the examples and defaults of
+// the schema are used when present
+func Synthesize(schema *openapi3.SchemaRef) interface{} {
+	return newSynthesizer().value(schema)
+}
+
+func (s *synthesizer) value(ref *openapi3.SchemaRef) interface{} {
+	if (ref == nil) || (ref.Value == nil) {
+		return nil
+	}
+
+	if ref.Ref != "" {
+		if s.visiting[ref.Ref] {
+			return nil
+		}
+
+		s.visiting[ref.Ref] = true
+		defer delete(s.visiting, ref.Ref)
+	}
+
+	schema := ref.Value
+
+	switch {
+	case schema.Example != nil:
+		return coerce(schema, schema.Example)
+	case schema.Default != nil:
+		return coerce(schema, schema.Default)
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.OneOf) > 0:
+		return s.value(schema.OneOf[0])
+	case len(schema.AnyOf) > 0:
+		return s.value(schema.AnyOf[0])
+	case len(schema.AllOf) > 0:
+		return s.allOf(schema)
+	}
+
+	switch schemaType(schema) {
+	case openapi3.TypeObject:
+		return s.object(schema)
+
+	case openapi3.TypeArray:
+		return s.array(schema)
+
+	case openapi3.TypeString:
+		return stringValue(schema)
+
+	case openapi3.TypeInteger:
+		return int64(math.Ceil(numberValue(schema)))
+
+	case openapi3.TypeNumber:
+		return numberValue(schema)
+
+	case openapi3.TypeBoolean:
+		return true
+	}
+
+	return nil
+}
+
+// coerce converts the examples set with the example tag, they are always
+// strings, to the type of schema
+func coerce(schema *openapi3.Schema, value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	switch schemaType(schema) {
+	case openapi3.TypeString, "":
+		return s
+
+	case openapi3.TypeInteger:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+
+	case openapi3.TypeNumber:
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+
+	case openapi3.TypeBoolean:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+
+	default:
+		var ret interface{}
+		if err := json.Unmarshal([]byte(s), &ret); err == nil {
+			return ret
+		}
+	}
+
+	return s
+}
+
+// schemaType returns the first type of schema which is not null
+func schemaType(schema *openapi3.Schema) string {
+	if schema.Type != nil {
+		for _, typ := range schema.Type.Slice() {
+			if typ != openapi3.TypeNull {
+				return typ
+			}
+		}
+	}
+
+	if len(schema.Properties) > 0 {
+		return openapi3.TypeObject
+	}
+
+	if schema.Items != nil {
+		return openapi3.TypeArray
+	}
+
+	return ""
+}
+
+func (s *synthesizer) object(schema *openapi3.Schema) interface{} {
+	ret := map[string]interface{}{}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := schema.Properties[name]
+
+		// only sent by the clients
+		if (property.Value != nil) && property.Value.WriteOnly {
+			continue
+		}
+
+		// the recursive references are left out
+		if value := s.value(property); value != nil {
+			ret[name] = value
+		}
+	}
+
+	return ret
+}
+
+func (s *synthesizer) array(schema *openapi3.Schema) interface{} {
+	count := int(schema.MinItems)
+	if count == 0 {
+		count = 1
+	}
+
+	ret := make([]interface{}, 0, count)
+
+	// a recursive item
+	item := s.value(schema.Items)
+	if item == nil {
+		return ret
+	}
+
+	for i := 0; i < count; i++ {
+		ret = append(ret, item)
+	}
+
+	return ret
+}
+
+// allOf merges the properties of the objects
+func (s *synthesizer) allOf(schema *openapi3.Schema) interface{} {
+	ret := map[string]interface{}{}
+
+	for _, ref := range schema.AllOf {
+		value := s.value(ref)
+
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			// not an object, the first value is used
+			return value
+		}
+
+		for k, v := range object {
+			ret[k] = v
+		}
+	}
+
+	return ret
+}
+
+func stringValue(schema *openapi3.Schema) string {
+	ret, found := _formatExamples[schema.Format]
+	if !found {
+		ret = "string"
+	}
+
+	if uint64(len(ret)) < schema.MinLength {
+		ret += strings.Repeat("a", int(schema.MinLength)-len(ret))
+	}
+
+	if (schema.MaxLength != nil) && (uint64(len(ret)) > *schema.MaxLength) {
+		ret = ret[:*schema.MaxLength]
+	}
+
+	return ret
+}
+
+func numberValue(schema *openapi3.Schema) float64 {
+	ret := 0.0
+
+	if schema.Min != nil {
+		ret = *schema.Min
+		if schema.ExclusiveMin {
+			ret++
+		}
+	} else if (schema.Max != nil) && (*schema.Max < ret) {
+		ret = *schema.Max
+		if schema.ExclusiveMax {
+			ret--
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,229 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/schmurfy/chipi/schema"
+	"github.com/schmurfy/chipi/shared"
+)
+
+// DefaultMultipartMemory is the size of the parts kept in memory when decoding
+// a multipart body, the rest is stored in temporary files
+const DefaultMultipartMemory = 32 << 20
+
+var (
+	_fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+	_readerType     = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	_bytesType      = reflect.TypeOf([]byte(nil))
+)
+
+// FormBodyDecoder decodes application/x-www-form-urlencoded bodies, the form
+// fields are mapped on the Body fields using their form or json name.
+type FormBodyDecoder struct{}
+
+func (d *FormBodyDecoder) DecodeRequestBody(r *http.Request, target interface{}, obj interface{}) error {
+	err := r.ParseForm()
+	if err != nil {
+		return err
+	}
+
+	return decodeForm(target, r.PostForm, nil)
+}
+
+// MultipartBodyDecoder decodes multipart/form-data bodies, the files can be received as
+// []byte, io.Reader or *multipart.FileHeader (or slices of those), their size
+// can be limited with the max-size tag (ex: `max-size:"5MB"`). The io.Reader files
+// are closed by the wrapper once the request is done.
+type MultipartBodyDecoder struct{}
+
+func (d *MultipartBodyDecoder) DecodeRequestBody(r *http.Request, target interface{}, obj interface{}) error {
+	err := r.ParseMultipartForm(DefaultMultipartMemory)
+	if err != nil {
+		return err
+	}
+
+	return decodeForm(target, r.MultipartForm.Value, r.MultipartForm.File)
+}
+
+// FormFieldName returns the name of the form field decoded in f, the form tag
+// has precedence over the json name (ex: `form:"file"`), "-" if ignored.
+func FormFieldName(f reflect.StructField) string {
+	if tag, found := f.Tag.Lookup("form"); found {
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name
+		}
+	}
+
+	tag := schema.ParseJsonTag(f)
+	if tag.GetIgnored() {
+		return "-"
+	}
+
+	return tag.Name
+}
+
+// IsFileType returns true if t can receive an uploaded file
+func IsFileType(t reflect.Type) bool {
+	switch t {
+	case _fileHeaderType, _readerType, _bytesType:
+		return true
+	}
+
+	if t.Kind() == reflect.Slice {
+		switch t.Elem() {
+		case _fileHeaderType, _readerType, _bytesType:
+			return true
+		}
+	}
+
+	return false
+}
+
+func decodeForm(target interface{}, values url.Values, files map[string][]*multipart.FileHeader) error {
+	v := reflect.ValueOf(target)
+	for (v.Kind() == reflect.Ptr) || (v.Kind() == reflect.Interface) {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("form body must be a structure, got %s", v.Kind())
+	}
+
+	for _, f := range reflect.VisibleFields(v.Type()) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+
+		name := FormFieldName(f)
+		if name == "-" {
+			continue
+		}
+
+		fv, err := v.FieldByIndexErr(f.Index)
+		if err != nil {
+			// nil embedded pointer
+			continue
+		}
+
+		switch {
+		case IsFileType(f.Type) && (len(files[name]) > 0):
+			err = setFiles(fv, f, files[name])
+
+		case len(values[name]) > 0:
+			err = setValues(fv, values[name])
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func setValues(fv reflect.Value, values []string) error {
+	// []byte fields sent as a simple value
+	if fv.Type() == _bytesType {
+		fv.SetBytes([]byte(values[0]))
+		return nil
+	}
+
+	// the field can be repeated (ex: tags=a&tags=b)
+	if (fv.Kind() == reflect.Slice) && (len(values) > 1) {
+		ret := reflect.MakeSlice(fv.Type(), 0, len(values))
+		for _, value := range values {
+			v, err := shared.ConvertValue(fv.Type().Elem(), value)
+			if err != nil {
+				return err
+			}
+			ret = reflect.Append(ret, v)
+		}
+
+		fv.Set(ret)
+		return nil
+	}
+
+	v, err := shared.ConvertValue(fv.Type(), values[0])
+	if err != nil {
+		return err
+	}
+
+	fv.Set(v)
+	return nil
+}
+
+func setFiles(fv reflect.Value, f reflect.StructField, headers []*multipart.FileHeader) error {
+	if maxSize, found := f.Tag.Lookup("max-size"); found {
+		limit, err := shared.ParseSize(maxSize)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range headers {
+			if h.Size > limit {
+				return fmt.Errorf("file %q is too large (%d bytes, max %d)", h.Filename, h.Size, limit)
+			}
+		}
+	}
+
+	if (f.Type.Kind() == reflect.Slice) && (f.Type != _bytesType) {
+		ret := reflect.MakeSlice(f.Type, 0, len(headers))
+		for _, h := range headers {
+			v, err := fileValue(f.Type.Elem(), h)
+			if err != nil {
+				return err
+			}
+			ret = reflect.Append(ret, v)
+		}
+
+		fv.Set(ret)
+		return nil
+	}
+
+	v, err := fileValue(f.Type, headers[0])
+	if err != nil {
+		return err
+	}
+
+	fv.Set(v)
+	return nil
+}
+
+func fileValue(t reflect.Type, h *multipart.FileHeader) (reflect.Value, error) {
+	switch t {
+	case _fileHeaderType:
+		return reflect.ValueOf(h), nil
+
+	case _readerType:
+		// closed by the wrapper, the temporary files are removed by net/http
+		// once the request is done
+		file, err := h.Open()
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		ret := reflect.New(_readerType).Elem()
+		ret.Set(reflect.ValueOf(file))
+		return ret, nil
+
+	default:
+		file, err := h.Open()
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		return reflect.ValueOf(data), nil
+	}
+}
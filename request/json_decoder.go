@@ -1,20 +1,123 @@
 package request
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 )
 
-type JsonBodyDecoder struct{}
+// DefaultMaxDepth is the maximum nesting of objects and arrays accepted
+// by JsonBodyDecoder when none is set
+const DefaultMaxDepth = 64
+
+// JsonBodyDecoder decodes json bodies, the options can be set on the
+// object given to the builder:
+//
+//	api.Post(r, "/pet", &CreatePetRequest{
+//		JsonBodyDecoder: request.JsonBodyDecoder{DisallowUnknownFields: true},
+//	})
+type JsonBodyDecoder struct {
+	// reject the bodies with fields not present in the Body structure
+	DisallowUnknownFields bool
+
+	// maximum nesting of objects and arrays, a negative value disables the check
+	MaxDepth int
+}
 
 func (d *JsonBodyDecoder) DecodeBody(body io.ReadCloser, target interface{}, obj interface{}) error {
-	// otherwise use the default decoder
-	decoder := json.NewDecoder(body)
-	err := decoder.Decode(&target)
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
 
 	// do not return an error on empty body
-	if err != nil && err.Error() == "EOF" {
+	if len(bytes.TrimSpace(data)) == 0 {
 		return nil
 	}
-	return err
+
+	maxDepth := d.maxDepth()
+	if (maxDepth > 0) && (jsonDepth(data) > maxDepth) {
+		return fmt.Errorf("json body is nested too deeply (max depth: %d)", maxDepth)
+	}
+
+	// otherwise use the default decoder
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if d.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	err = decoder.Decode(&target)
+	if err != nil {
+		return err
+	}
+
+	// only one document is expected
+	_, err = decoder.Token()
+	if !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after json body")
+	}
+
+	return nil
+}
+
+// BodyExtensions documents the options in the specification
+func (d *JsonBodyDecoder) BodyExtensions() map[string]interface{} {
+	ret := map[string]interface{}{}
+
+	if d.DisallowUnknownFields {
+		ret["x-disallow-unknown-fields"] = true
+	}
+
+	if maxDepth := d.maxDepth(); maxDepth > 0 {
+		ret["x-max-depth"] = maxDepth
+	}
+
+	return ret
+}
+
+func (d *JsonBodyDecoder) maxDepth() int {
+	if d.MaxDepth == 0 {
+		return DefaultMaxDepth
+	}
+
+	return d.MaxDepth
+}
+
+// jsonDepth returns the maximum nesting of objects and arrays in data
+func jsonDepth(data []byte) int {
+	depth := 0
+	ret := 0
+	inString := false
+	escaped := false
+
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+
+		case c == '"':
+			inString = true
+
+		case (c == '{') || (c == '['):
+			depth++
+			if depth > ret {
+				ret = depth
+			}
+
+		case (c == '}') || (c == ']'):
+			depth--
+		}
+	}
+
+	return ret
 }
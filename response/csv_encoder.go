@@ -0,0 +1,146 @@
+package response
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/schmurfy/chipi/schema"
+)
+
+// CsvEncoder encodes a slice or a sequence (iter.Seq[T] and channels) of structures
+// as csv, the first line holds the json names of the fields and complex values
+// are encoded as json.
+type CsvEncoder struct{}
+
+func (e *CsvEncoder) EncodeResponse(ctx context.Context, w http.ResponseWriter, obj interface{}) {
+	if v := reflect.ValueOf(obj); (v.Kind() == reflect.Ptr) && !v.IsNil() {
+		obj = v.Elem().Interface()
+	}
+
+	fields, header, err := csvFields(obj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	setContentType(w, "text/csv")
+
+	cw := csv.NewWriter(w)
+	flusher := newPeriodicFlusher(w)
+	flusher.beforeFlush = cw.Flush
+
+	err = cw.Write(header)
+	if err != nil {
+		return
+	}
+
+	err = eachItem(ctx, obj, func(item interface{}) error {
+		record, err := csvRecord(reflect.ValueOf(item), fields)
+		if err != nil {
+			return err
+		}
+
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+
+		flusher.itemWritten()
+		return nil
+	})
+
+	// a truncated file is better than a missing error
+	if err != nil {
+		return
+	}
+
+	flusher.flush()
+}
+
+func csvFields(obj interface{}) ([]reflect.StructField, []string, error) {
+	typ, ok := itemType(obj)
+	if !ok {
+		return nil, nil, fmt.Errorf("csv: slice expected, got %T", obj)
+	}
+
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("csv: slice of structures expected, got %s", typ.Kind())
+	}
+
+	fields := []reflect.StructField{}
+	header := []string{}
+	for _, f := range reflect.VisibleFields(typ) {
+		tag := schema.ParseJsonTag(f)
+		if !f.IsExported() || f.Anonymous || tag.GetIgnored() {
+			continue
+		}
+
+		fields = append(fields, f)
+		header = append(header, tag.Name)
+	}
+
+	return fields, header, nil
+}
+
+func csvRecord(item reflect.Value, fields []reflect.StructField) ([]string, error) {
+	for (item.Kind() == reflect.Ptr) || (item.Kind() == reflect.Interface) {
+		if item.IsNil() {
+			break
+		}
+		item = item.Elem()
+	}
+
+	record := make([]string, len(fields))
+	if item.Kind() != reflect.Struct {
+		return record, nil
+	}
+
+	for i, f := range fields {
+		fv, err := item.FieldByIndexErr(f.Index)
+		if err != nil {
+			// nil embedded pointer
+			continue
+		}
+
+		record[i], err = csvValue(fv)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+func csvValue(v reflect.Value) (string, error) {
+	for (v.Kind() == reflect.Ptr) || (v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		data, err := m.MarshalText()
+		return string(data), err
+	}
+
+	switch v.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), nil
+	}
+
+	data, err := json.Marshal(v.Interface())
+	return string(data), err
+}
@@ -2,11 +2,27 @@ package response
 
 import (
 	"context"
+	"errors"
 	"net/http"
 )
 
+// ErrorEncoder writes the error message with a 400 status, errors with a
+// StatusCode() method choose their status (the message of server
+// errors is not sent).
 type ErrorEncoder struct{}
 
 func (e *ErrorEncoder) HandleError(ctx context.Context, w http.ResponseWriter, err error) {
-	http.Error(w, err.Error(), http.StatusBadRequest)
+	status := http.StatusBadRequest
+
+	var withStatus interface{ StatusCode() int }
+	if errors.As(err, &withStatus) {
+		status = withStatus.StatusCode()
+	}
+
+	if status >= http.StatusInternalServerError {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	http.Error(w, err.Error(), status)
 }
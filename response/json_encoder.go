@@ -6,10 +6,17 @@ import (
 	"net/http"
 )
 
+// JsonEncoder encodes the response as json, sequences (iter.Seq[T] and channels)
+// are written as an array item by item.
 type JsonEncoder struct{}
 
 func (e *JsonEncoder) EncodeResponse(ctx context.Context, w http.ResponseWriter, obj interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+	setContentType(w, "application/json")
+
+	if isSequence(obj) {
+		encodeJsonArray(ctx, w, obj)
+		return
+	}
 
 	err := json.NewEncoder(w).Encode(obj)
 	if err != nil {
@@ -18,3 +25,49 @@ func (e *JsonEncoder) EncodeResponse(ctx context.Context, w http.ResponseWriter,
 	}
 
 }
+
+// the array is left unterminated if the sequence fails so the client
+// cannot mistake it for a complete response
+func encodeJsonArray(ctx context.Context, w http.ResponseWriter, obj interface{}) {
+	flusher := newPeriodicFlusher(w)
+	first := true
+
+	_, err := w.Write([]byte("["))
+	if err != nil {
+		return
+	}
+
+	err = eachItem(ctx, obj, func(item interface{}) error {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			data = append([]byte(","), data...)
+		}
+		first = false
+
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+
+		flusher.itemWritten()
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	_, _ = w.Write([]byte("]\n"))
+	flusher.flush()
+}
+
+// keep the content type chosen by the negotiation if any
+func setContentType(w http.ResponseWriter, contentType string) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+}
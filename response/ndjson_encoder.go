@@ -0,0 +1,34 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// NDJSONEncoder writes one json document per line, slices and sequences
+// (iter.Seq[T] and channels) are written item by item.
+type NDJSONEncoder struct{}
+
+func (e *NDJSONEncoder) EncodeResponse(ctx context.Context, w http.ResponseWriter, obj interface{}) {
+	setContentType(w, "application/x-ndjson")
+
+	flusher := newPeriodicFlusher(w)
+	encoder := json.NewEncoder(w)
+
+	err := eachItem(ctx, obj, func(item interface{}) error {
+		err := encoder.Encode(item)
+		if err != nil {
+			return err
+		}
+
+		flusher.itemWritten()
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	flusher.flush()
+}
@@ -0,0 +1,110 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/schmurfy/chipi/shared"
+)
+
+// ErrNotAcceptable is returned when the client accepts none of the available content types
+var ErrNotAcceptable = errors.New("not acceptable")
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	ret := []acceptRange{}
+
+	for _, part := range strings.Split(accept, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := params["q"]; found {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		ret = append(ret, acceptRange{mediaType: mediaType, quality: quality})
+	}
+
+	return ret
+}
+
+// the quality of the most specific range matching mediaType, -1 if none does
+func offerQuality(ranges []acceptRange, mediaType string) float64 {
+	quality := -1.0
+	specificity := 0
+
+	for _, r := range ranges {
+		s := 0
+		switch {
+		case r.mediaType == mediaType:
+			s = 3
+		case strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(r.mediaType, "*")):
+			s = 2
+		case r.mediaType == "*/*":
+			s = 1
+		}
+
+		if s > specificity {
+			specificity = s
+			quality = r.quality
+		}
+	}
+
+	return quality
+}
+
+// NegotiateContentType returns the offer preferred by the Accept header, the
+// defaultOffer is used when the client has no preference (or no Accept header)
+func NegotiateContentType(accept string, offers []string, defaultOffer string) (string, error) {
+	if len(offers) == 0 {
+		return "", fmt.Errorf("%w: no content type available", ErrNotAcceptable)
+	}
+
+	if !shared.Contains(offers, defaultOffer) {
+		defaultOffer = offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return defaultOffer, nil
+	}
+
+	best := ""
+	bestQuality := 0.0
+
+	for _, offer := range offers {
+		mediaType, _, err := mime.ParseMediaType(offer)
+		if err != nil {
+			mediaType = offer
+		}
+
+		quality := offerQuality(ranges, strings.ToLower(mediaType))
+		if (quality > bestQuality) || ((quality == bestQuality) && (quality > 0) && (offer == defaultOffer)) {
+			best = offer
+			bestQuality = quality
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("%w: %q, available: %s", ErrNotAcceptable, accept, strings.Join(offers, ", "))
+	}
+
+	return best, nil
+}
@@ -0,0 +1,160 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/schmurfy/chipi/shared"
+)
+
+// number of items written between two flushes
+const _flushEvery = 100
+
+// isSequence returns true for the values which are encoded item by item without
+// being loaded in memory: iter.Seq[T], channels and streams
+func isSequence(obj interface{}) bool {
+	if _, ok := obj.(StreamInterface); ok {
+		return true
+	}
+
+	t := reflect.TypeOf(obj)
+	if t == nil {
+		return false
+	}
+
+	_, ok := shared.SequenceItemType(t)
+	return ok
+}
+
+// eachItem calls fn for every item of the sequence (or slice), it stops
+// at the first error or when ctx is done.
+func eachItem(ctx context.Context, obj interface{}, fn func(item interface{}) error) error {
+	if stream, ok := obj.(StreamInterface); ok {
+		return stream.Run(ctx, func(ev StreamEvent) error {
+			return fn(ev.Data)
+		})
+	}
+
+	v := reflect.ValueOf(obj)
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch {
+	case (v.Kind() == reflect.Slice) || (v.Kind() == reflect.Array):
+		for i := 0; i < v.Len(); i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			err := fn(v.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+
+	case v.Kind() == reflect.Chan:
+		if v.IsNil() {
+			return nil
+		}
+
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: v},
+		}
+
+		for {
+			chosen, item, ok := reflect.Select(cases)
+			if chosen == 0 {
+				return ctx.Err()
+			}
+
+			if !ok {
+				return nil
+			}
+
+			err := fn(item.Interface())
+			if err != nil {
+				return err
+			}
+		}
+
+	// the streams and channels are handled above
+	case isSequence(obj):
+		if v.IsNil() {
+			return nil
+		}
+
+		var err error
+		yield := reflect.MakeFunc(v.Type().In(0), func(args []reflect.Value) []reflect.Value {
+			err = ctx.Err()
+			if err == nil {
+				err = fn(args[0].Interface())
+			}
+
+			return []reflect.Value{reflect.ValueOf(err == nil)}
+		})
+
+		v.Call([]reflect.Value{yield})
+		return err
+
+	default:
+		return fn(obj)
+	}
+
+	return nil
+}
+
+// periodicFlusher sends the buffered data to the client every few items
+type periodicFlusher struct {
+	controller *http.ResponseController
+	count      int
+
+	// called before flushing, for encoders with their own buffer
+	beforeFlush func()
+}
+
+func newPeriodicFlusher(w http.ResponseWriter) *periodicFlusher {
+	return &periodicFlusher{
+		controller: http.NewResponseController(w),
+	}
+}
+
+func (f *periodicFlusher) itemWritten() {
+	f.count++
+	if (f.count % _flushEvery) == 0 {
+		f.flush()
+	}
+}
+
+func (f *periodicFlusher) flush() {
+	if f.beforeFlush != nil {
+		f.beforeFlush()
+	}
+
+	// not every writer supports it
+	_ = f.controller.Flush()
+}
+
+// the type of the items of a slice or sequence
+func itemType(obj interface{}) (reflect.Type, bool) {
+	if stream, ok := obj.(StreamInterface); ok {
+		return stream.ItemType(), true
+	}
+
+	t := reflect.TypeOf(obj)
+	if t == nil {
+		return nil, false
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if (t.Kind() == reflect.Slice) || (t.Kind() == reflect.Array) {
+		return t.Elem(), true
+	}
+
+	return shared.SequenceItemType(t)
+}
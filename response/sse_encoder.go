@@ -0,0 +1,149 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// new lines would end the field
+var _sseFieldReplacer = strings.NewReplacer("\n", " ", "\r", " ")
+
+// DefaultHeartbeat is the interval between two heartbeats when none is set
+const DefaultHeartbeat = 15 * time.Second
+
+// SSEEncoder sends a Stream as server-sent events, the data of each event
+// is encoded as json. A comment is sent when the stream is idle to keep the
+// connection open and the stream is stopped when the client goes away.
+type SSEEncoder struct {
+	// interval between heartbeats, a negative value disables them
+	Heartbeat time.Duration
+}
+
+func (e *SSEEncoder) EncodeResponse(ctx context.Context, w http.ResponseWriter, obj interface{}) {
+	stream, ok := obj.(StreamInterface)
+	if !ok {
+		http.Error(w, fmt.Sprintf("sse: stream expected, got %T", obj), http.StatusInternalServerError)
+		return
+	}
+
+	setContentType(w, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable proxy buffering (nginx)
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sw := &sseWriter{
+		w:          w,
+		controller: http.NewResponseController(w),
+	}
+
+	// flush the headers so the client knows the stream started
+	err := sw.flush()
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	heartbeat := e.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = DefaultHeartbeat
+	}
+
+	// nothing must be written once we return
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	if heartbeat > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sw.heartbeats(ctx, cancel, heartbeat)
+		}()
+	}
+
+	err = stream.Run(ctx, func(ev StreamEvent) error {
+		// the client is gone
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return sw.writeEvent(ev)
+	})
+
+	if (err != nil) && (ctx.Err() == nil) {
+		_ = sw.writeEvent(StreamEvent{Name: "error", Data: err.Error()})
+	}
+}
+
+// sseWriter serializes the writes of the events and the heartbeats
+type sseWriter struct {
+	lock       sync.Mutex
+	w          io.Writer
+	controller *http.ResponseController
+}
+
+func (sw *sseWriter) flush() error {
+	err := sw.controller.Flush()
+	if err == http.ErrNotSupported {
+		return nil
+	}
+	return err
+}
+
+func (sw *sseWriter) writeEvent(ev StreamEvent) error {
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+
+	frame := &strings.Builder{}
+	if ev.ID != "" {
+		fmt.Fprintf(frame, "id: %s\n", _sseFieldReplacer.Replace(ev.ID))
+	}
+	if ev.Name != "" {
+		fmt.Fprintf(frame, "event: %s\n", _sseFieldReplacer.Replace(ev.Name))
+	}
+	fmt.Fprintf(frame, "data: %s\n\n", data)
+
+	return sw.write(frame.String())
+}
+
+func (sw *sseWriter) write(s string) error {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+
+	_, err := io.WriteString(sw.w, s)
+	if err != nil {
+		return err
+	}
+
+	return sw.flush()
+}
+
+func (sw *sseWriter) heartbeats(ctx context.Context, cancel context.CancelFunc, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			err := sw.write(": heartbeat\n\n")
+			if err != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package response
+
+import (
+	"context"
+	"reflect"
+)
+
+// Event is a message sent on a stream, only Data is required
+type Event[T any] struct {
+	// sent as the event id and event type with server-sent events
+	ID   string
+	Name string
+
+	Data T
+}
+
+// StreamEvent is an Event with its data type erased, as seen by the encoders
+type StreamEvent struct {
+	ID   string
+	Name string
+	Data interface{}
+}
+
+// StreamInterface is implemented by Stream, it lets the encoders
+// and the builder work on any stream type.
+type StreamInterface interface {
+	Run(ctx context.Context, send func(StreamEvent) error) error
+	ItemType() reflect.Type
+}
+
+// Stream is used as Response type for long-lived operations pushing events,
+// Handle sets it and the events are sent by the encoder as they are produced.
+//
+//	Response response.Stream[Event]
+type Stream[T any] struct {
+	run func(ctx context.Context, send func(Event[T]) error) error
+}
+
+// NewStream creates a stream from a function called by the encoder, send returns an
+// error when the client is gone and the function must return when ctx is done.
+func NewStream[T any](run func(ctx context.Context, send func(Event[T]) error) error) Stream[T] {
+	return Stream[T]{run: run}
+}
+
+// NewChannelStream creates a stream sending every value read from ch until
+// it is closed.
+func NewChannelStream[T any](ch <-chan T) Stream[T] {
+	return NewStream(func(ctx context.Context, send func(Event[T]) error) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+
+			case data, ok := <-ch:
+				if !ok {
+					return nil
+				}
+
+				err := send(Event[T]{Data: data})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+func (s Stream[T]) Run(ctx context.Context, send func(StreamEvent) error) error {
+	if s.run == nil {
+		return nil
+	}
+
+	return s.run(ctx, func(ev Event[T]) error {
+		return send(StreamEvent{
+			ID:   ev.ID,
+			Name: ev.Name,
+			Data: ev.Data,
+		})
+	})
+}
+
+// ItemType returns the type of the events data
+func (s Stream[T]) ItemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
@@ -0,0 +1,52 @@
+package response
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"reflect"
+)
+
+// XmlEncoder encodes the response with encoding/xml, slices are
+// wrapped in an <items> element
+type XmlEncoder struct{}
+
+func (e *XmlEncoder) EncodeResponse(ctx context.Context, w http.ResponseWriter, obj interface{}) {
+	setContentType(w, "application/xml")
+
+	data, err := marshalXml(obj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
+
+func marshalXml(obj interface{}) ([]byte, error) {
+	v := reflect.ValueOf(obj)
+	for (v.Kind() == reflect.Ptr) && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if (v.Kind() == reflect.Slice) || (v.Kind() == reflect.Array) {
+		return xml.Marshal(struct {
+			XMLName xml.Name      `xml:"items"`
+			Items   []interface{} `xml:"item"`
+		}{
+			Items: sliceItems(v),
+		})
+	}
+
+	return xml.Marshal(obj)
+}
+
+func sliceItems(v reflect.Value) []interface{} {
+	ret := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		ret = append(ret, v.Index(i).Interface())
+	}
+
+	return ret
+}
@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"io"
+	"math/big"
+	"mime/multipart"
+	"net"
+	"net/netip"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/schmurfy/chipi/shared"
+)
+
+var (
+	_textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	_jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	_durationType      = reflect.TypeOf(time.Duration(0))
+
+	_builtinTypesLock sync.RWMutex
+	_builtinTypes     = map[reflect.Type]func() *openapi3.Schema{
+		reflect.TypeOf(time.Time{}):       openapi3.NewDateTimeSchema,
+		_durationType:                     openapi3.NewInt64Schema,
+		reflect.TypeOf(json.RawMessage{}): openapi3.NewSchema,
+		reflect.TypeOf(net.IP{}):          NewIPSchema,
+		reflect.TypeOf(netip.Addr{}):      NewIPSchema,
+		reflect.TypeOf(netip.AddrPort{}):  openapi3.NewStringSchema,
+		reflect.TypeOf(netip.Prefix{}):    openapi3.NewStringSchema,
+		reflect.TypeOf(big.Int{}):         openapi3.NewIntegerSchema,
+		reflect.TypeOf(big.Float{}):       openapi3.NewStringSchema,
+		reflect.TypeOf(big.Rat{}):         openapi3.NewStringSchema,
+		reflect.TypeOf(json.Number("")):   openapi3.NewFloat64Schema,
+
+		// uploaded files
+		reflect.TypeOf(multipart.FileHeader{}):   NewBinarySchema,
+		reflect.TypeOf((*io.Reader)(nil)).Elem(): NewBinarySchema,
+	}
+)
+
+// RegisterType defines the schema used whenever t is found, it takes
+// precedence over everything else (including the type layout).
+// ex: schema.RegisterType(reflect.TypeOf(civil.Date{}), schema.NewDateSchema)
+func RegisterType(t reflect.Type, newSchema func() *openapi3.Schema) {
+	_builtinTypesLock.Lock()
+	defer _builtinTypesLock.Unlock()
+
+	_builtinTypes[t] = newSchema
+}
+
+func NewDateSchema() *openapi3.Schema {
+	return &openapi3.Schema{
+		Type:   shared.GetPtr(openapi3.Types{openapi3.TypeString}),
+		Format: "date",
+	}
+}
+
+// a duration as accepted by time.ParseDuration (ex: "1h30m"), used for the
+// time.Duration parameters (the bodies encode it as nanoseconds)
+func newDurationSchema() *openapi3.Schema {
+	return &openapi3.Schema{
+		Type:   shared.GetPtr(openapi3.Types{openapi3.TypeString}),
+		Format: "duration",
+	}
+}
+
+// raw bytes (ex: an uploaded file)
+func NewBinarySchema() *openapi3.Schema {
+	return &openapi3.Schema{
+		Type:   shared.GetPtr(openapi3.Types{openapi3.TypeString}),
+		Format: "binary",
+	}
+}
+
+// an ip address, v4 or v6
+func NewIPSchema() *openapi3.Schema {
+	return &openapi3.Schema{
+		Type: shared.GetPtr(openapi3.Types{openapi3.TypeString}),
+		AnyOf: openapi3.SchemaRefs{
+			openapi3.NewSchemaRef("", &openapi3.Schema{Format: "ipv4"}),
+			openapi3.NewSchemaRef("", &openapi3.Schema{Format: "ipv6"}),
+		},
+	}
+}
+
+func builtinSchema(t reflect.Type) (*openapi3.Schema, bool) {
+	_builtinTypesLock.RLock()
+	defer _builtinTypesLock.RUnlock()
+
+	if newSchema, found := _builtinTypes[t]; found {
+		return newSchema(), true
+	}
+
+	return nil, false
+}
+
+// types with a custom serialization are documented by their wire format
+// instead of their go layout.
+func marshalerSchema(t reflect.Type) (*openapi3.Schema, bool) {
+	if t.Kind() == reflect.Interface {
+		return nil, false
+	}
+
+	ptr := reflect.PointerTo(t)
+
+	switch {
+	// encoding/json prefers MarshalJSON when both are implemented
+	case ptr.Implements(_jsonMarshalerType):
+		// nothing can be known about the output
+		return openapi3.NewSchema(), true
+
+	case ptr.Implements(_textMarshalerType):
+		return openapi3.NewStringSchema(), true
+	}
+
+	return nil, false
+}
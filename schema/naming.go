@@ -0,0 +1,224 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	_invalidComponentChars = regexp.MustCompile(`[^a-zA-Z0-9\.\-_]`)
+)
+
+// NamingStrategy decides the name of the components created for go types
+type NamingStrategy interface {
+	ComponentName(t reflect.Type) string
+}
+
+type NamingStrategyFunc func(t reflect.Type) string
+
+func (f NamingStrategyFunc) ComponentName(t reflect.Type) string {
+	return f(t)
+}
+
+var (
+	// package name and type name (ex: models.Pet), this is the default
+	ShortNaming NamingStrategy = NamingStrategyFunc(typeName)
+
+	// full import path and type name (ex: github.com_schmurfy_chipi_models.Pet)
+	FullPathNaming NamingStrategy = NamingStrategyFunc(fullPathTypeName)
+)
+
+// CollisionPolicy decides what happens when two different types
+// get the same component name
+type CollisionPolicy int
+
+const (
+	// return an error, this is the default
+	CollisionError CollisionPolicy = iota
+	// add a numbered suffix to the name (ex: models.Pet_2)
+	CollisionDisambiguate
+)
+
+func (s *Schema) SetNamingStrategy(naming NamingStrategy) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.naming = naming
+}
+
+func (s *Schema) SetCollisionPolicy(policy CollisionPolicy) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.collisionPolicy = policy
+}
+
+// componentName returns the name used to store t in the components, the same
+// type always gets the same name.
+func (s *Schema) componentName(t reflect.Type) (string, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if name, found := s.types[t]; found {
+		return name, nil
+	}
+
+	name := s.naming.ComponentName(t)
+
+	if other, found := s.names[name]; found {
+		switch s.collisionPolicy {
+		case CollisionDisambiguate:
+			for i := 2; found; i++ {
+				candidate := fmt.Sprintf("%s_%d", name, i)
+				_, found = s.names[candidate]
+				if !found {
+					name = candidate
+				}
+			}
+
+		default:
+			return "", fmt.Errorf("component name collision: %q is used by %s.%s and %s.%s",
+				name, other.PkgPath(), other.Name(), t.PkgPath(), t.Name())
+		}
+	}
+
+	s.names[name] = t
+	s.types[t] = name
+
+	return name, nil
+}
+
+func componentReference(name string) string {
+	return fmt.Sprintf("#/components/schemas/%s", name)
+}
+
+func typeName(t reflect.Type) string {
+	return formatTypeName(t, pkgName)
+}
+
+func fullPathTypeName(t reflect.Type) string {
+	return formatTypeName(t, func(p string) string {
+		return strings.ReplaceAll(p, "/", "_")
+	})
+}
+
+func pkgName(p string) string {
+	parts := strings.Split(p, "/")
+	return parts[len(parts)-1]
+}
+
+func typePkgName(t reflect.Type) string {
+	return pkgName(t.PkgPath())
+}
+
+// When using generic structures reflect's type name looks something like
+// genericStruct[full/module/path/module.subType,other/module.subType2[int]]
+// OpenAPI does not support "[]" or "/", so we replace this by
+// module.genericStruct..module.subType..module.subType2..int
+func formatTypeName(t reflect.Type, formatPkg func(string) string) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Name() == "" {
+		return _invalidComponentChars.ReplaceAllString(t.String(), "_")
+	}
+
+	qualifiedName := t.Name()
+	if t.PkgPath() != "" {
+		qualifiedName = t.PkgPath() + "." + t.Name()
+	}
+
+	return _invalidComponentChars.ReplaceAllString(formatTypeString(qualifiedName, formatPkg), "_")
+}
+
+// format a fully qualified type as found in reflect's type names
+func formatTypeString(s string, formatPkg func(string) string) string {
+	switch {
+	case strings.HasPrefix(s, "*"):
+		return formatTypeString(s[1:], formatPkg)
+
+	case strings.HasPrefix(s, "[]"):
+		return "Slice-" + formatTypeString(s[2:], formatPkg)
+
+	case strings.HasPrefix(s, "map["):
+		end := closingBracket(s, len("map"))
+		return "Map-" + formatTypeString(s[len("map["):end], formatPkg) + "-" + formatTypeString(s[end+1:], formatPkg)
+
+	case strings.HasPrefix(s, "["):
+		end := closingBracket(s, 0)
+		return "Array" + s[1:end] + "-" + formatTypeString(s[end+1:], formatPkg)
+	}
+
+	name := s
+	args := ""
+	if start := strings.Index(s, "["); start != -1 {
+		name = s[:start]
+		args = s[start+1 : closingBracket(s, start)]
+	}
+
+	// the package path may contain dots (ex: github.com/...)
+	lastSlash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[lastSlash+1:], "."); dot != -1 {
+		dot += lastSlash + 1
+		name = formatPkg(name[:dot]) + name[dot:]
+	}
+
+	if args == "" {
+		return name
+	}
+
+	parts := []string{name}
+	for _, arg := range splitTypeArguments(args) {
+		parts = append(parts, formatTypeString(arg, formatPkg))
+	}
+
+	return strings.Join(parts, "..")
+}
+
+// return the position of the bracket closing the one at start
+func closingBracket(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return len(s)
+}
+
+// split type arguments on commas, ignoring those of nested types
+func splitTypeArguments(s string) []string {
+	ret := []string{}
+	depth := 0
+	last := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				ret = append(ret, strings.TrimSpace(s[last:i]))
+				last = i + 1
+			}
+		}
+	}
+
+	return append(ret, strings.TrimSpace(s[last:]))
+}
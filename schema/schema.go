@@ -4,30 +4,60 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"regexp"
-	"strings"
-	"time"
+	"sync"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/schmurfy/chipi/shared"
 )
 
-var (
-	_timeType         = reflect.TypeOf(time.Time{})
-	_genericTypeRegex = regexp.MustCompile(`(.+)\[(.+)\]`)
-)
-
 type Schema struct {
+	naming          NamingStrategy
+	collisionPolicy CollisionPolicy
+
+	lock  sync.Mutex
+	names map[string]reflect.Type
+	types map[reflect.Type]string
 }
 
 func New() (*Schema, error) {
-	return &Schema{}, nil
+	return &Schema{
+		naming: ShortNaming,
+		names:  make(map[string]reflect.Type),
+		types:  make(map[reflect.Type]string),
+	}, nil
 }
 
 func (s *Schema) GenerateSchemaFor(ctx context.Context, doc *openapi3.T, t reflect.Type) (*openapi3.SchemaRef, error) {
 	return s.generateSchemaFor(ctx, doc, t, 0, shared.AttributeInfo{}, shared.NewChipiCallbacks(nil))
 }
 
+// GenerateParameterSchemaFor is GenerateSchemaFor for the path, query and header
+// parameters which are decoded from text: a time.Duration (or a slice of them)
+// is a duration string (ex: "1h30m") instead of nanoseconds.
+func (s *Schema) GenerateParameterSchemaFor(ctx context.Context, doc *openapi3.T, t reflect.Type) (*openapi3.SchemaRef, error) {
+	base := derefType(t)
+
+	if base == _durationType {
+		return openapi3.NewSchemaRef("", newDurationSchema()), nil
+	}
+
+	if (base.Kind() == reflect.Slice) && (derefType(base.Elem()) == _durationType) {
+		ret := openapi3.NewArraySchema()
+		ret.Items = openapi3.NewSchemaRef("", newDurationSchema())
+		return openapi3.NewSchemaRef("", ret), nil
+	}
+
+	return s.GenerateSchemaFor(ctx, doc, t)
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
 func (s *Schema) GenerateFilteredSchemaFor(ctx context.Context, doc *openapi3.T, t reflect.Type, callbacksObject shared.ChipiCallbacks) (*openapi3.SchemaRef, error) {
 	return s.generateSchemaFor(ctx, doc, t, 0, shared.AttributeInfo{}, callbacksObject)
 }
@@ -72,8 +102,6 @@ func (s *Schema) generateSchemaWithCastedType(ctx context.Context, doc *openapi3
 }
 
 func (s *Schema) generateSchemaFor(ctx context.Context, doc *openapi3.T, t reflect.Type, inlineLevel int, fieldInfo shared.AttributeInfo, callbacksObject shared.ChipiCallbacks) (*openapi3.SchemaRef, error) {
-	fullName := typeName(t)
-
 	if !fieldInfo.Empty() {
 		filter, err := callbacksObject.FilterField(ctx, fieldInfo)
 		if err != nil {
@@ -92,6 +120,21 @@ func (s *Schema) generateSchemaFor(ctx context.Context, doc *openapi3.T, t refle
 		t = t.Elem()
 	}
 
+	if value, found := builtinSchema(t); found {
+		schema.Value = value
+		return s.resolveEnum(doc, t, schema, callbacksObject)
+	}
+
+	// the type knows how to describe itself
+	if provider, ok := reflect.New(t).Interface().(shared.SchemaProviderInterface); ok {
+		return s.generateProvidedSchema(ctx, doc, t, provider, inlineLevel, callbacksObject)
+	}
+
+	if value, found := marshalerSchema(t); found {
+		schema.Value = value
+		return s.resolveEnum(doc, t, schema, callbacksObject)
+	}
+
 	switch t.Kind() {
 
 	// basic types
@@ -104,11 +147,22 @@ func (s *Schema) generateSchemaFor(ctx context.Context, doc *openapi3.T, t refle
 	case reflect.Int8, reflect.Int16, reflect.Int32:
 		schema.Value = openapi3.NewInt32Schema()
 
-	case reflect.Int, reflect.Int64,
-		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	case reflect.Int, reflect.Int64:
 		schema.Value = openapi3.NewInt64Schema()
 
-	case reflect.Float32, reflect.Float64:
+	case reflect.Uint8, reflect.Uint16:
+		schema.Value = openapi3.NewInt32Schema().WithMin(0)
+
+	case reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		schema.Value = openapi3.NewInt64Schema().WithMin(0)
+
+	case reflect.Float32:
+		schema.Value = &openapi3.Schema{
+			Type:   shared.GetPtr(openapi3.Types{openapi3.TypeNumber}),
+			Format: "float",
+		}
+
+	case reflect.Float64:
 		schema.Value = &openapi3.Schema{
 			Type:   shared.GetPtr(openapi3.Types{openapi3.TypeNumber}),
 			Format: "double",
@@ -143,6 +197,23 @@ func (s *Schema) generateSchemaFor(ctx context.Context, doc *openapi3.T, t refle
 
 		}
 
+	// sequences streamed by the encoders (ex: iter.Seq[T], chan T)
+	case reflect.Chan, reflect.Func:
+		itemType, ok := shared.SequenceItemType(t)
+		if !ok {
+			return nil, fmt.Errorf("unknown type: %v", t.Kind())
+		}
+
+		items, err := s.generateSchemaFor(ctx, doc, itemType, 0, fieldInfo, callbacksObject)
+		if err != nil {
+			return nil, err
+		}
+
+		schema.Value = &openapi3.Schema{
+			Type:  shared.GetPtr(openapi3.Types{openapi3.TypeArray}),
+			Items: items,
+		}
+
 	case reflect.Map:
 		additionalProperties, err := s.generateSchemaFor(ctx, doc, t.Elem(), 0, fieldInfo, callbacksObject)
 		if err != nil {
@@ -158,11 +229,6 @@ func (s *Schema) generateSchemaFor(ctx context.Context, doc *openapi3.T, t refle
 
 	// struct schemas should be stored as components
 	case reflect.Struct:
-		if t == _timeType {
-			schema.Value = openapi3.NewDateTimeSchema()
-			return schema, nil
-		}
-
 		if doc.Components == nil {
 			doc.Components = &openapi3.Components{}
 		}
@@ -177,10 +243,14 @@ func (s *Schema) generateSchemaFor(ctx context.Context, doc *openapi3.T, t refle
 			return schema, err
 		}
 
+		fullName, err := s.componentName(t)
+		if err != nil {
+			return nil, err
+		}
+
 		// check if the structure already exists as component first
 		_, found := doc.Components.Schemas[fullName]
 		if !found {
-			var err error
 			ref := &openapi3.SchemaRef{}
 
 			// forward declaration of the current type to handle recursion properly
@@ -192,7 +262,7 @@ func (s *Schema) generateSchemaFor(ctx context.Context, doc *openapi3.T, t refle
 			}
 		}
 
-		schema.Ref = schemaReference(t)
+		schema.Ref = componentReference(fullName)
 	case reflect.Interface:
 		schema.Value = openapi3.NewSchema()
 
@@ -200,61 +270,161 @@ func (s *Schema) generateSchemaFor(ctx context.Context, doc *openapi3.T, t refle
 		return nil, fmt.Errorf("unknown type: %v", t.Kind())
 	}
 
-	// Handle the case of enums
+	return s.resolveEnum(doc, t, schema, callbacksObject)
+}
+
+// Handle the case of enums
+func (s *Schema) resolveEnum(doc *openapi3.T, t reflect.Type, schema *openapi3.SchemaRef, callbacksObject shared.ChipiCallbacks) (*openapi3.SchemaRef, error) {
 	if isEnum, enum := callbacksObject.EnumResolver(t); isEnum {
+		fullName, err := s.componentName(t)
+		if err != nil {
+			return nil, err
+		}
+
+		if doc.Components == nil {
+			doc.Components = &openapi3.Components{}
+		}
+		if doc.Components.Schemas == nil {
+			doc.Components.Schemas = make(openapi3.Schemas)
+		}
+
 		_, found := doc.Components.Schemas[fullName]
 		if !found {
 			enumDescriptions := []any{}
+			enumComments := []any{}
+			hasComments := false
 			for _, enumEntry := range enum {
 				schema.Value.Enum = append(schema.Value.Enum, enumEntry.Value)
 				enumDescriptions = append(enumDescriptions, enumEntry.Title)
+				enumComments = append(enumComments, enumEntry.Description)
+				hasComments = hasComments || (enumEntry.Description != "")
 			}
 			if len(enumDescriptions) > 0 {
 				schema.Value.Extensions = map[string]any{
 					"x-enum-varnames": enumDescriptions,
 				}
 			}
+			if hasComments {
+				schema.Value.Extensions["x-enum-descriptions"] = enumComments
+			}
 			doc.Components.Schemas[fullName] = &openapi3.SchemaRef{
 				Value: schema.Value,
 			}
 		}
 
-		schema.Ref = schemaReference(t)
+		schema.Ref = componentReference(fullName)
 		schema.Value = nil
 	}
 
 	return schema, nil
 }
 
-func typeName(t reflect.Type) string {
-	for t.Kind() == reflect.Ptr {
-		t = t.Elem()
+func (s *Schema) generateProvidedSchema(ctx context.Context, doc *openapi3.T, t reflect.Type, provider shared.SchemaProviderInterface, inlineLevel int, callbacksObject shared.ChipiCallbacks) (*openapi3.SchemaRef, error) {
+	fullName, err := s.componentName(t)
+	if err != nil {
+		return nil, err
 	}
 
-	typeName := t.String()
+	if doc.Components == nil {
+		doc.Components = &openapi3.Components{}
+	}
+	if doc.Components.Schemas == nil {
+		doc.Components.Schemas = make(openapi3.Schemas)
+	}
 
-	// When using generic structures reflect's type name looks something like
-	// module.genericStruct[full/module/path/module.subType]
-	// OpenAPI does not support "[]" or "/", so we replace this by module.genericStruct..module.subType
-	match := _genericTypeRegex.FindStringSubmatch(typeName)
-	if match == nil {
-		return typeName
-	} else {
-		return fmt.Sprintf("%s..%s", match[1], pkgName(match[2]))
+	if _, found := doc.Components.Schemas[fullName]; !found {
+		ref := &openapi3.SchemaRef{}
+
+		// forward declaration of the current type to handle recursion properly
+		doc.Components.Schemas[fullName] = ref
+
+		var createRef bool
+		ref.Value, createRef = provider.ChipiSchema(s.newGenerateSchemaCallback(ctx, doc, inlineLevel, callbacksObject))
+		if !createRef {
+			delete(doc.Components.Schemas, fullName)
+			return &openapi3.SchemaRef{Value: ref.Value}, nil
+		}
 	}
+
+	return &openapi3.SchemaRef{
+		Ref: componentReference(fullName),
+	}, nil
 }
 
-func schemaReference(t reflect.Type) string {
-	return fmt.Sprintf("#/components/schemas/%s", typeName(t))
+// returns the annotations generated by chipi-gen from the comments
+// of the structure (field is empty) or one of its fields
+func schemaAnnotations(t reflect.Type, field string) *openapi3.Schema {
+	if t.Name() == "" {
+		return nil
+	}
+
+	method, found := reflect.PointerTo(t).MethodByName("CHIPI_Schema_Annotations")
+	if !found {
+		return nil
+	}
+
+	ret := method.Func.Call([]reflect.Value{
+		reflect.New(t),
+		reflect.ValueOf(field),
+	})
+
+	if s, ok := ret[0].Interface().(*openapi3.Schema); ok {
+		return s
+	}
+
+	return nil
 }
 
-func pkgName(p string) string {
-	parts := strings.Split(p, "/")
-	return parts[len(parts)-1]
+// tags have precedence over comments
+func applySchemaAnnotations(tag *jsonTag, annotations *openapi3.Schema) {
+	if annotations == nil {
+		return
+	}
+
+	if (tag.Description == nil) && (annotations.Description != "") {
+		tag.Description = stringPtr(annotations.Description)
+	}
+
+	if example, ok := annotations.Example.(string); ok && (tag.Example == nil) {
+		tag.Example = stringPtr(example)
+	}
+
+	if (tag.Deprecated == nil) && annotations.Deprecated {
+		tag.Deprecated = boolPtr(true)
+	}
 }
 
-func typePkgName(t reflect.Type) string {
-	return pkgName(t.PkgPath())
+// `json:",string"` only applies to strings, numbers and booleans (or pointers to them)
+// the value is then encoded as a json string
+func stringEncodedSchema(t reflect.Type, fieldSchema *openapi3.SchemaRef) *openapi3.SchemaRef {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var pattern string
+
+	switch t.Kind() {
+	case reflect.String:
+	case reflect.Bool:
+		pattern = `^(true|false)$`
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		pattern = `^-?[0-9]+$`
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		pattern = `^[0-9]+$`
+	case reflect.Float32, reflect.Float64:
+		pattern = `^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`
+	default:
+		// ignored by encoding/json
+		return fieldSchema
+	}
+
+	ret := openapi3.NewStringSchema()
+	ret.Pattern = pattern
+	if fieldSchema.Value != nil {
+		ret.Format = fieldSchema.Value.Format
+	}
+
+	return openapi3.NewSchemaRef("", ret)
 }
 
 func (s *Schema) generateStructureSchema(ctx context.Context, doc *openapi3.T, t reflect.Type, inlineLevel int, fieldInfo shared.AttributeInfo, callbacksObject shared.ChipiCallbacks) (*openapi3.Schema, error) {
@@ -278,13 +448,14 @@ func (s *Schema) generateStructureSchema(ctx context.Context, doc *openapi3.T, t
 
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
-		fTypeName := typeName(f.Type)
 		tag := ParseJsonTag(f)
 
 		if !f.IsExported() || (tag.Ignored != nil) && *tag.Ignored {
 			continue
 		}
 
+		applySchemaAnnotations(tag, schemaAnnotations(t, f.Name))
+
 		fieldName := shared.ToSnakeCase(f.Name)
 		fi := fieldInfo.
 			WithModelPath(pkgName + "." + structName + "." + fieldName).
@@ -305,14 +476,25 @@ func (s *Schema) generateStructureSchema(ctx context.Context, doc *openapi3.T, t
 			continue
 		}
 
+		if tag.GetAsString() {
+			fieldSchema = stringEncodedSchema(f.Type, fieldSchema)
+		}
+
 		//Detect if field is anonymous, look into the schemas and use the same property
-		if f.Anonymous && fieldSchema.Ref != "" && doc.Components.Schemas[fTypeName] != nil && doc.Components.Schemas[fTypeName].Value != nil {
-			for name, property := range doc.Components.Schemas[fTypeName].Value.Properties {
-				ret.WithPropertyRef(name, property)
+		if f.Anonymous && fieldSchema.Ref != "" {
+			fTypeName, err := s.componentName(f.Type)
+			if err != nil {
+				return nil, err
 			}
 
-			//Ignore the anonymous field
-			continue
+			if doc.Components.Schemas[fTypeName] != nil && doc.Components.Schemas[fTypeName].Value != nil {
+				for name, property := range doc.Components.Schemas[fTypeName].Value.Properties {
+					ret.WithPropertyRef(name, property)
+				}
+
+				//Ignore the anonymous field
+				continue
+			}
 		}
 
 		if fieldSchema.Ref != "" {
@@ -366,5 +548,12 @@ func (s *Schema) generateStructureSchema(ctx context.Context, doc *openapi3.T, t
 		ret.Type = shared.GetPtr(openapi3.Types{openapi3.TypeObject})
 	}
 
+	// structure comments
+	if annotations := schemaAnnotations(t, ""); annotations != nil {
+		ret.Description = annotations.Description
+		ret.Example = annotations.Example
+		ret.Deprecated = annotations.Deprecated
+	}
+
 	return ret, nil
 }
@@ -4,7 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
+	"math/big"
+	"net"
+	"net/netip"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -36,6 +41,96 @@ type Generic[T any] struct {
 type Embedded struct {
 }
 
+type Pair[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+type UUID [16]byte
+
+func (UUID) ChipiSchema(_ shared.GenerateSchemaCallbackType) (*openapi3.Schema, bool) {
+	return openapi3.NewUUIDSchema(), false
+}
+
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func (*Money) ChipiSchema(newSchema shared.GenerateSchemaCallbackType) (*openapi3.Schema, bool) {
+	currency, _ := newSchema(reflect.TypeOf(""), shared.AttributeInfo{})
+
+	ret := openapi3.NewObjectSchema().
+		WithProperty("amount", openapi3.NewStringSchema().WithPattern(`^-?[0-9]+\.[0-9]{2}$`)).
+		WithPropertyRef("currency", currency)
+	ret.Description = "an amount of money"
+
+	return ret, true
+}
+
+type Level int
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(int(l))), nil
+}
+
+type Point struct {
+	X, Y float64
+}
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]float64{p.X, p.Y})
+}
+
+// encoding/json uses MarshalJSON
+func (p Point) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%f,%f", p.X, p.Y)), nil
+}
+
+type Cat struct {
+	Name  string
+	Lives int `description:"from tag"`
+	Owner *RecursiveUser
+}
+
+func (*Cat) CHIPI_Schema_Annotations(field string) *openapi3.Schema {
+	switch field {
+	case "":
+		return &openapi3.Schema{Description: "a cat"}
+	case "Name":
+		return &openapi3.Schema{Description: "its name", Example: "Felix"}
+	case "Lives":
+		return &openapi3.Schema{Description: "from comment"}
+	case "Owner":
+		return &openapi3.Schema{Deprecated: true}
+	}
+
+	return nil
+}
+
+type Status string
+
+const (
+	StatusOpen   Status = "open"
+	StatusClosed Status = "closed"
+)
+
+func (Status) CHIPI_Enum() shared.Enum {
+	return shared.Enum{
+		{Title: "StatusOpen", Value: StatusOpen, Description: "still open"},
+		{Title: "StatusClosed", Value: StatusClosed},
+	}
+}
+
+type Ticket struct {
+	Status Status
+}
+
+type Invoice struct {
+	Id    UUID
+	Total Money
+}
+
 func checkGeneratedType(g *goblin.G, ctx context.Context, schemaPtr **Schema, docPtr **openapi3.T, value interface{}, expected string) {
 	g.It(fmt.Sprintf("should generate inline type for %T", value), func() {
 		s := *schemaPtr
@@ -136,6 +231,11 @@ func TestSchema(t *testing.T) {
 				3.14:     `{"type": "number", "format": "double"}`,
 				int32(2): `{"type": "integer", "format": "int32"}`,
 				int(42):  `{"type": "integer", "format": "int64"}`,
+
+				float32(1.2): `{"type": "number", "format": "float"}`,
+				uint8(2):     `{"type": "integer", "format": "int32", "minimum": 0}`,
+				uint(2):      `{"type": "integer", "format": "int64", "minimum": 0}`,
+				uint64(2):    `{"type": "integer", "format": "int64", "minimum": 0}`,
 			}
 
 			for value, expected := range tests {
@@ -167,18 +267,84 @@ func TestSchema(t *testing.T) {
 						"format": "int32"
 					}
 				}`},
+
+				{Name: "iter.Seq[string]", Value: iter.Seq[string](nil), Expected: `{
+					"type": "array", "items": {
+						"type": "string"
+					}
+				}`},
+
+				{Name: "chan bool", Value: make(chan bool), Expected: `{
+					"type": "array", "items": {
+						"type": "boolean"
+					}
+				}`},
+			}
+
+			for _, tt := range tests {
+				checkGeneratedType(g, ctx, &s, &doc, tt.Value, tt.Expected)
+			}
+		})
+
+		g.Describe("builtin types", func() {
+			tests := []struct {
+				Value    interface{}
+				Expected string
+			}{
+				{Value: time.Duration(0), Expected: `{"type": "integer", "format": "int64"}`},
+				{Value: json.RawMessage{}, Expected: `{}`},
+				{Value: net.IP{}, Expected: `{"type": "string", "anyOf": [{"format": "ipv4"}, {"format": "ipv6"}]}`},
+				{Value: netip.Addr{}, Expected: `{"type": "string", "anyOf": [{"format": "ipv4"}, {"format": "ipv6"}]}`},
+				{Value: big.Int{}, Expected: `{"type": "integer"}`},
+				{Value: Level(0), Expected: `{"type": "string"}`},
+				{Value: Point{}, Expected: `{}`},
 			}
 
 			for _, tt := range tests {
 				checkGeneratedType(g, ctx, &s, &doc, tt.Value, tt.Expected)
 			}
+
+			g.It("should document the duration parameters as strings", func() {
+				tests := map[reflect.Type]string{
+					reflect.TypeOf(time.Duration(0)):   `{"type": "string", "format": "duration"}`,
+					reflect.TypeOf(new(time.Duration)): `{"type": "string", "format": "duration"}`,
+					reflect.TypeOf([]time.Duration{}):  `{"type": "array", "items": {"type": "string", "format": "duration"}}`,
+					reflect.TypeOf(0):                  `{"type": "integer", "format": "int64"}`,
+				}
+
+				for typ, expected := range tests {
+					schema, err := s.GenerateParameterSchemaFor(ctx, doc, typ)
+					require.NoError(g, err)
+
+					data, err := json.Marshal(schema)
+					require.NoError(g, err)
+					assert.JSONEq(g, expected, string(data), typ.String())
+				}
+			})
+
+			g.It("should use registered types", func() {
+				type Date struct {
+					Year, Month, Day int
+				}
+
+				RegisterType(reflect.TypeOf(Date{}), NewDateSchema)
+
+				schema, err := s.GenerateSchemaFor(ctx, doc, reflect.TypeOf(&Date{}))
+				require.NoError(g, err)
+
+				data, err := json.Marshal(schema)
+				require.NoError(g, err)
+
+				assert.JSONEq(g, `{"type": "string", "format": "date"}`, string(data))
+			})
 		})
 
 		g.Describe("different packages", func() {
 			g.It("should generate correct reference path", func() {
 				typ1 := reflect.TypeOf(monster.QueryResponse{})
-				path := schemaReference(typ1)
-				assert.Equal(g, "#/components/schemas/monster.QueryResponse", path)
+				schema, err := s.GenerateSchemaFor(ctx, doc, typ1)
+				require.NoError(g, err)
+				assert.Equal(g, "#/components/schemas/monster.QueryResponse", schema.Ref)
 			})
 
 			g.It("should generate correct types for same structure name", func() {
@@ -194,6 +360,72 @@ func TestSchema(t *testing.T) {
 			})
 		})
 
+		g.Describe("naming", func() {
+			newThing := func() reflect.Type {
+				type Thing struct{ A int }
+				return reflect.TypeOf(Thing{})
+			}
+			newOtherThing := func() reflect.Type {
+				type Thing struct{ B int }
+				return reflect.TypeOf(Thing{})
+			}
+
+			g.It("should name generics with multiple and nested parameters", func() {
+				typ := reflect.TypeOf(Generic[Pair[string, []*Embedded]]{})
+				assert.Equal(g, "schema.Generic..schema.Pair..string..Slice-schema.Embedded", typeName(typ))
+
+				typ = reflect.TypeOf(Pair[monster.Monster, map[string]pet.Pet]{})
+				assert.Equal(g, "schema.Pair..monster.Monster..Map-string-pet.Pet", typeName(typ))
+			})
+
+			g.It("should use full import path", func() {
+				s.SetNamingStrategy(FullPathNaming)
+
+				schema, err := s.GenerateSchemaFor(ctx, doc, reflect.TypeOf(monster.QueryResponse{}))
+				require.NoError(g, err)
+
+				assert.Equal(g, "#/components/schemas/github.com_schmurfy_chipi_internal_testdata_monster.QueryResponse", schema.Ref)
+			})
+
+			g.It("should use custom naming", func() {
+				s.SetNamingStrategy(NamingStrategyFunc(func(t reflect.Type) string {
+					return "Custom" + t.Name()
+				}))
+
+				schema, err := s.GenerateSchemaFor(ctx, doc, reflect.TypeOf(monster.QueryResponse{}))
+				require.NoError(g, err)
+
+				assert.Equal(g, "#/components/schemas/CustomQueryResponse", schema.Ref)
+			})
+
+			g.It("should return an error on collision", func() {
+				_, err := s.GenerateSchemaFor(ctx, doc, newThing())
+				require.NoError(g, err)
+
+				_, err = s.GenerateSchemaFor(ctx, doc, newOtherThing())
+				require.Error(g, err)
+				assert.Contains(g, err.Error(), "collision")
+			})
+
+			g.It("should disambiguate collisions", func() {
+				s.SetCollisionPolicy(CollisionDisambiguate)
+
+				schema1, err := s.GenerateSchemaFor(ctx, doc, newThing())
+				require.NoError(g, err)
+
+				schema2, err := s.GenerateSchemaFor(ctx, doc, newOtherThing())
+				require.NoError(g, err)
+
+				assert.Equal(g, "#/components/schemas/schema.Thing", schema1.Ref)
+				assert.Equal(g, "#/components/schemas/schema.Thing_2", schema2.Ref)
+
+				// names are stable
+				schema1, err = s.GenerateSchemaFor(ctx, doc, newThing())
+				require.NoError(g, err)
+				assert.Equal(g, "#/components/schemas/schema.Thing", schema1.Ref)
+			})
+		})
+
 		g.Describe("structures", func() {
 			type UserGender int
 			type WrappedTime struct {
@@ -290,6 +522,31 @@ func TestSchema(t *testing.T) {
 				}`, string(ref))
 			})
 
+			g.It("should use enums declared by the type", func() {
+				_, err := s.GenerateSchemaFor(ctx, doc, reflect.TypeOf(Ticket{}))
+				require.NoError(g, err)
+
+				data, err := json.Marshal(doc.Components.Schemas["schema.Ticket"])
+				require.NoError(g, err)
+
+				assert.JSONEq(g, `{
+					"type": "object",
+					"properties": {
+						"Status": {"$ref": "#/components/schemas/schema.Status"}
+					}
+				}`, string(data))
+
+				data, err = json.Marshal(doc.Components.Schemas["schema.Status"])
+				require.NoError(g, err)
+
+				assert.JSONEq(g, `{
+					"type": "string",
+					"enum": ["open", "closed"],
+					"x-enum-varnames": ["StatusOpen", "StatusClosed"],
+					"x-enum-descriptions": ["still open", ""]
+				}`, string(data))
+			})
+
 			g.It("should generate referenced type for user", func() {
 				typ := reflect.TypeOf(&User{})
 				schema, err := s.GenerateFilteredSchemaFor(ctx, doc, typ, shared.NewChipiCallbacks(&TestEnumResolver{}))
@@ -327,6 +584,54 @@ func TestSchema(t *testing.T) {
 				}`, string(data))
 			})
 
+			g.It("should honor json string option", func() {
+				type Account struct {
+					Id      int64   `json:"id,string"`
+					Balance float64 `json:",string"`
+					Flags   *uint32 `json:"flags,string,omitempty"`
+					Active  bool    `json:"active,string"`
+					Name    string  `json:"-,"`
+				}
+
+				_, err := s.GenerateSchemaFor(ctx, doc, reflect.TypeOf(Account{}))
+				require.NoError(g, err)
+
+				data, err := json.Marshal(doc.Components.Schemas["schema.Account"])
+				require.NoError(g, err)
+
+				assert.JSONEq(g, `{
+					"type": "object",
+					"properties": {
+						"id": {"type": "string", "format": "int64", "pattern": "^-?[0-9]+$"},
+						"Balance": {"type": "string", "format": "double", "pattern": "^-?[0-9]+(\\.[0-9]+)?([eE][-+]?[0-9]+)?$"},
+						"flags": {"type": "string", "format": "int64", "pattern": "^[0-9]+$"},
+						"active": {"type": "string", "pattern": "^(true|false)$"},
+						"-": {"type": "string"}
+					}
+				}`, string(data))
+			})
+
+			g.It("should use annotations generated from comments", func() {
+				_, err := s.GenerateSchemaFor(ctx, doc, reflect.TypeOf(Cat{}))
+				require.NoError(g, err)
+
+				data, err := json.Marshal(doc.Components.Schemas["schema.Cat"])
+				require.NoError(g, err)
+
+				assert.JSONEq(g, `{
+					"type": "object",
+					"description": "a cat",
+					"properties": {
+						"Name": {"type": "string", "description": "its name", "example": "Felix"},
+						"Lives": {"type": "integer", "format": "int64", "description": "from tag"},
+						"Owner": {
+							"deprecated": true,
+							"allOf": [{"$ref": "#/components/schemas/schema.RecursiveUser"}]
+						}
+					}
+				}`, string(data))
+			})
+
 			g.It("should handle recursive structures", func() {
 
 				g.Timeout(5 * time.Second)
@@ -445,6 +750,52 @@ func TestSchema(t *testing.T) {
 				}`, string(data))
 			})
 
+			g.Describe("types describing themselves", func() {
+				g.It("should inline schema if no ref is requested", func() {
+					schema, err := s.GenerateSchemaFor(ctx, doc, reflect.TypeOf(UUID{}))
+					require.NoError(g, err)
+
+					data, err := json.Marshal(schema)
+					require.NoError(g, err)
+
+					assert.JSONEq(g, `{"type": "string", "format": "uuid"}`, string(data))
+				})
+
+				g.It("should use schemas in structures", func() {
+					_, err := s.GenerateSchemaFor(ctx, doc, reflect.TypeOf(&Invoice{}))
+					require.NoError(g, err)
+
+					invoiceSchema, found := doc.Components.Schemas["schema.Invoice"]
+					require.True(g, found)
+
+					data, err := json.Marshal(invoiceSchema)
+					require.NoError(g, err)
+
+					assert.JSONEq(g, `{
+						"type": "object",
+						"properties": {
+							"Id": {"type": "string", "format": "uuid"},
+							"Total": {"$ref": "#/components/schemas/schema.Money"}
+						}
+					}`, string(data))
+
+					moneySchema, found := doc.Components.Schemas["schema.Money"]
+					require.True(g, found)
+
+					data, err = json.Marshal(moneySchema)
+					require.NoError(g, err)
+
+					assert.JSONEq(g, `{
+						"type": "object",
+						"description": "an amount of money",
+						"properties": {
+							"amount": {"type": "string", "pattern": "^-?[0-9]+\\.[0-9]{2}$"},
+							"currency": {"type": "string"}
+						}
+					}`, string(data))
+				})
+			})
+
 			checkGeneratedType(g, ctx, &s, &doc, time.Time{}, `{
 				"type": "string",
 				"format": "date-time"
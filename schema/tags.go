@@ -10,12 +10,14 @@ type jsonTag struct {
 
 	// from json or chipi tag
 	OmitEmpty  *bool
+	AsString   *bool
 	ReadOnly   *bool
 	WriteOnly  *bool
 	Nullable   *bool
 	Ignored    *bool
 	Deprecated *bool
 	Required   *bool
+	Sensitive  *bool
 	CastName   *string
 
 	// self contained
@@ -31,6 +33,12 @@ func (t *jsonTag) GetOmitEmpty() bool {
 	}
 	return *t.OmitEmpty
 }
+func (t *jsonTag) GetAsString() bool {
+	if t.AsString == nil {
+		return false
+	}
+	return *t.AsString
+}
 func (t *jsonTag) GetReadOnly() bool {
 	if t.ReadOnly == nil {
 		return false
@@ -73,6 +81,12 @@ func (t *jsonTag) GetRequired() bool {
 	}
 	return *t.Required
 }
+func (t *jsonTag) GetSensitive() bool {
+	if t.Sensitive == nil {
+		return false
+	}
+	return *t.Sensitive
+}
 func (t *jsonTag) GetCastName() string {
 	if t.CastName == nil {
 		return ""
@@ -97,16 +111,22 @@ func ParseJsonTag(f reflect.StructField) *jsonTag {
 		Name: f.Name,
 	}
 
+	// same rules as encoding/json: the first value is the name
+	// and the following ones are options
 	if tag, found := f.Tag.Lookup("json"); found {
 		values := strings.Split(tag, ",")
-		for _, value := range values {
+		if tag == "-" {
+			ret.Ignored = boolPtr(true)
+		} else if values[0] != "" {
+			ret.Name = values[0]
+		}
+
+		for _, value := range values[1:] {
 			switch value {
-			case "-":
-				ret.Ignored = boolPtr(true)
 			case "omitempty":
 				ret.OmitEmpty = boolPtr(true)
-			default:
-				ret.Name = value
+			case "string":
+				ret.AsString = boolPtr(true)
 			}
 		}
 	}
@@ -127,6 +147,8 @@ func ParseJsonTag(f reflect.StructField) *jsonTag {
 				ret.Deprecated = boolPtr(true)
 			case "required":
 				ret.Required = boolPtr(true)
+			case "sensitive":
+				ret.Sensitive = boolPtr(true)
 			default:
 				if strings.HasPrefix(value, "as:") {
 					castName := strings.TrimPrefix(value, "as:")
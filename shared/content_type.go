@@ -0,0 +1,14 @@
+package shared
+
+import "sort"
+
+// SortedContentTypes returns the content types handled by the decoders (or encoders) in a stable order
+func SortedContentTypes[T any](decoders map[string]T) []string {
+	ret := make([]string, 0, len(decoders))
+	for contentType := range decoders {
+		ret = append(ret, contentType)
+	}
+
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,126 @@
+package shared
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_noValue = reflect.Value{}
+
+	_textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	_durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// TrimQuotes removes the quotes surrounding value if any
+func TrimQuotes(value string) string {
+	if len(value) > 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1] // remove quotes only if they are at the beginning AND the end
+	}
+	return value
+}
+
+// ConvertValue parses value as found in a parameter or form field into
+// a value of type fieldType
+func ConvertValue(fieldType reflect.Type, value string) (reflect.Value, error) {
+	// types knowing how to parse themselves (ex: time.Time, netip.Addr)
+	if (fieldType.Kind() != reflect.Ptr) && reflect.PointerTo(fieldType).Implements(_textUnmarshalerType) {
+		setValuePtr := reflect.New(fieldType)
+		err := setValuePtr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(TrimQuotes(value)))
+		if err != nil {
+			return _noValue, err
+		}
+		return setValuePtr.Elem(), nil
+	}
+
+	// accept both "1h30m" and a number of nanoseconds
+	if fieldType == _durationType {
+		if d, err := time.ParseDuration(TrimQuotes(value)); err == nil {
+			return reflect.ValueOf(d), nil
+		}
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Ptr:
+		fieldType := fieldType.Elem()
+		setValue, err := ConvertValue(fieldType, value)
+		if err != nil {
+			return _noValue, err
+		}
+		setValuePtr := reflect.New(fieldType)
+		setValuePtr.Elem().Set(setValue)
+		return setValuePtr, nil
+	case reflect.Map:
+		outMapPtr := reflect.New(fieldType)
+		err := json.Unmarshal([]byte(value), outMapPtr.Interface())
+		if err != nil {
+			return _noValue, err
+		}
+		return outMapPtr.Elem(), nil
+	case reflect.Slice:
+		param := strings.Split(
+			strings.Trim(value, `[]`),
+			",")
+		sliceType := fieldType.Elem()
+		setValue := reflect.New(reflect.SliceOf(sliceType)).Elem()
+		for _, v := range param {
+			vv, err := ConvertValue(sliceType, strings.TrimSpace(v))
+			if err != nil {
+				return _noValue, err
+			}
+			setValue = reflect.Append(setValue, vv)
+		}
+		return setValue, nil
+
+	case reflect.Struct:
+		setValue := reflect.New(fieldType)
+		iface := setValue.Interface()
+		err := json.Unmarshal([]byte(value), &iface)
+		if err != nil {
+			return _noValue, err
+		}
+		return setValue.Elem(), nil
+
+	case reflect.String:
+		return reflect.ValueOf(TrimQuotes(value)).Convert(fieldType), nil
+
+	case reflect.Bool:
+		setValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return _noValue, err
+		}
+		return reflect.ValueOf(setValue).Convert(fieldType), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return _noValue, err
+		}
+		setValue := reflect.ValueOf(n).Convert(fieldType)
+		return setValue, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return _noValue, err
+		}
+		setValue := reflect.ValueOf(n).Convert(fieldType)
+		return setValue, nil
+
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return _noValue, err
+		}
+		setValue := reflect.ValueOf(x).Convert(fieldType)
+		return setValue, nil
+
+	default:
+		return reflect.Value{}, fmt.Errorf("invalid type: %v", fieldType.Kind())
+	}
+}
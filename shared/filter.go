@@ -54,11 +54,18 @@ func (ai AttributeInfo) AppendPath(segment string) AttributeInfo {
 }
 
 type EnumEntry struct {
-	Title interface{}
-	Value interface{}
+	Title       interface{}
+	Value       interface{}
+	Description string
 }
 type Enum = []EnumEntry
 
+// EnumInterface is implemented by the types used as enums, the method is
+// generated by chipi-gen for typed constants
+type EnumInterface interface {
+	CHIPI_Enum() Enum
+}
+
 // This object can implement FilterRoute/FilterField/EnumResolver/SchemaResolver
 type ChipiCallbackInterface interface {
 }
@@ -83,6 +90,14 @@ type SchemaResolverInterface interface {
 	SchemaResolver(fieldInfo AttributeInfo, castName string, fieldTyp reflect.Type, newSchemaCallbackType GenerateSchemaCallbackType) (*openapi3.Schema, bool)
 }
 
+// SchemaProviderInterface can be implemented by any type to describe its own schema
+// instead of letting chipi inspect its layout (ex: uuid.UUID, decimal.Decimal).
+// The callback can be used to generate the schema of other types.
+// The boolean decide if we create a $ref in the openapi file
+type SchemaProviderInterface interface {
+	ChipiSchema(newSchemaCallback GenerateSchemaCallbackType) (*openapi3.Schema, bool)
+}
+
 type ExtraComponentsAndPathsInterface interface {
 	ExtraComponentsAndPaths() (openapi3.Schemas, openapi3.Paths)
 }
@@ -120,12 +135,22 @@ func (c *ChipiCallbacks) FilterField(ctx context.Context, fieldInfo AttributeInf
 	}
 }
 
+// EnumResolver uses the callbacks object first and then the type
+// itself if it implements EnumInterface
 func (c *ChipiCallbacks) EnumResolver(t reflect.Type) (bool, Enum) {
 	if enumInterface, hasEnum := c.i.(EnumResolverInterface); c.i != nil && hasEnum {
-		return enumInterface.EnumResolver(t)
-	} else {
-		return false, nil
+		if isEnum, enum := enumInterface.EnumResolver(t); isEnum {
+			return true, enum
+		}
+	}
+
+	if t.Kind() != reflect.Interface {
+		if enumInterface, hasEnum := reflect.New(t).Interface().(EnumInterface); hasEnum {
+			return true, enumInterface.CHIPI_Enum()
+		}
 	}
+
+	return false, nil
 }
 
 func (c *ChipiCallbacks) SchemaResolver(fieldInfo AttributeInfo, castName string, fieldTyp reflect.Type, newSchemaCallbackType GenerateSchemaCallbackType) (*openapi3.Schema, bool) {
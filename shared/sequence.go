@@ -0,0 +1,24 @@
+package shared
+
+import "reflect"
+
+// SequenceItemType returns the type of the values of a channel or an
+// iterator (func(yield func(T) bool), ex: iter.Seq[T])
+func SequenceItemType(t reflect.Type) (reflect.Type, bool) {
+	switch t.Kind() {
+	case reflect.Chan:
+		return t.Elem(), true
+
+	case reflect.Func:
+		if (t.NumIn() != 1) || (t.NumOut() != 0) {
+			return nil, false
+		}
+
+		yield := t.In(0)
+		if (yield.Kind() == reflect.Func) && (yield.NumIn() == 1) && (yield.NumOut() == 1) && (yield.Out(0).Kind() == reflect.Bool) {
+			return yield.In(0), true
+		}
+	}
+
+	return nil, false
+}
@@ -1,7 +1,9 @@
 package shared
 
 import (
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -15,3 +17,35 @@ func ToSnakeCase(str string) string {
 	snake = matchAllCap.ReplaceAllString(snake, "${1}_${2}")
 	return strings.ToLower(snake)
 }
+
+var _sizeUnits = map[string]int64{
+	"":   1,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+}
+
+// ParseSize parses a size like "512", "10KB" or "5MB" (1KB = 1024 bytes)
+func ParseSize(value string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(value))
+
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0') || (r > '9')
+	})
+	if i == -1 {
+		i = len(str)
+	}
+
+	unit, found := _sizeUnits[strings.TrimSpace(str[i:])]
+	if !found || (i == 0) {
+		return 0, fmt.Errorf("invalid size: %q", value)
+	}
+
+	n, err := strconv.ParseInt(str[:i], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return n * unit, nil
+}
@@ -0,0 +1,43 @@
+package wrapper
+
+import (
+	"log/slog"
+
+	"github.com/schmurfy/chipi/shared"
+)
+
+// Config holds the settings shared by every operation of a builder,
+// it is read on each request so it can be changed after the routes
+// were registered, but not once the requests are served (there is no
+// synchronization).
+type Config struct {
+	// used to validate the enums values, the spec uses the same source
+	Callbacks shared.ChipiCallbacks
+
+	// maximum size of the request bodies in bytes, 0 means no limit
+	// (the max-size tag of the Body field has precedence)
+	MaxBodySize int64
+
+	// used for the requests without error handler, encoder or decoder,
+	// the decoder is either a BodyDecoder or a BodyDecoderWithRequest
+	ErrorHandler    ErrorHandlerInterface
+	ResponseEncoder ResponseEncoder
+	BodyDecoder     interface{}
+
+	// run around Handle for every operation, before their own interceptors
+	Interceptors []Interceptor
+
+	// logs the decoding failures, the errors and the panics, the requests
+	// are not logged if nil
+	Logger *slog.Logger
+
+	// panic again after recording the panic on the span instead of
+	// calling the error handler (useful in development)
+	Repanic bool
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Callbacks: shared.NewChipiCallbacks(nil),
+	}
+}
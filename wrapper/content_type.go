@@ -0,0 +1,118 @@
+package wrapper
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/schmurfy/chipi/response"
+	"github.com/schmurfy/chipi/shared"
+)
+
+// ErrUnsupportedMediaType is returned when no decoder accepts the request Content-Type
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// IsBodyDecoder returns true if decoder can be used to decode a body
+func IsBodyDecoder(decoder interface{}) bool {
+	switch decoder.(type) {
+	case BodyDecoder, BodyDecoderWithRequest:
+		return true
+	}
+
+	return false
+}
+
+func decodeBody(decoder interface{}, r *http.Request, target interface{}, obj interface{}) error {
+	switch d := decoder.(type) {
+	case BodyDecoderWithRequest:
+		return d.DecodeRequestBody(r, target, obj)
+	case BodyDecoder:
+		return d.DecodeBody(r.Body, target, obj)
+	}
+
+	return fmt.Errorf("%T is not a body decoder", decoder)
+}
+
+// selectBodyDecoder returns the decoder matching the request Content-Type, the
+// keys can use wildcards (ex: "application/*" or "*/*")
+func selectBodyDecoder(r *http.Request, decoders map[string]interface{}) (interface{}, error) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return nil, fmt.Errorf("%w: missing content type, expected one of: %s",
+			ErrUnsupportedMediaType, strings.Join(shared.SortedContentTypes(decoders), ", "))
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, err.Error())
+	}
+
+	candidates := []string{mediaType, "*/*"}
+	if i := strings.Index(mediaType, "/"); i != -1 {
+		candidates = []string{mediaType, mediaType[:i] + "/*", "*/*"}
+	}
+
+	for _, candidate := range candidates {
+		for contentType, decoder := range decoders {
+			accepted, _, err := mime.ParseMediaType(contentType)
+			if err != nil {
+				accepted = contentType
+			}
+
+			if strings.EqualFold(accepted, candidate) {
+				return decoder, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q, expected one of: %s",
+		ErrUnsupportedMediaType, mediaType, strings.Join(shared.SortedContentTypes(decoders), ", "))
+}
+
+// DefaultResponseContentType is the content type used when the client has no preference, it is
+// set with the content-type tag of the Response field and defaults to application/json
+func DefaultResponseContentType(obj interface{}) string {
+	typ := reflect.TypeOf(obj)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() == reflect.Struct {
+		if f, found := typ.FieldByName("Response"); found {
+			if contentType, found := f.Tag.Lookup("content-type"); found {
+				return contentType
+			}
+		}
+	}
+
+	return "application/json"
+}
+
+// selectResponseEncoder returns the encoder to use for the response and the
+// negotiated content type if the operation has several encoders, the
+// default encoder is used if it has none
+func selectResponseEncoder(r *http.Request, obj interface{}, config *Config) (ResponseEncoder, string, error) {
+	if encoders, ok := obj.(ResponseEncodersInterface); ok {
+		list := encoders.ResponseEncoders()
+
+		contentType, err := response.NegotiateContentType(
+			r.Header.Get("Accept"),
+			shared.SortedContentTypes(list),
+			DefaultResponseContentType(obj),
+		)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return list[contentType], contentType, nil
+	}
+
+	if encoder, ok := obj.(ResponseEncoder); ok {
+		return encoder, "", nil
+	}
+
+	return config.ResponseEncoder, "", nil
+}
@@ -0,0 +1,220 @@
+package wrapper
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/schmurfy/chipi/schema"
+	"github.com/schmurfy/chipi/shared"
+)
+
+// checkEnum validates a parameter value, slices are checked element by element
+func checkEnum(callbacks shared.ChipiCallbacks, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if isEnum, enum := callbacks.EnumResolver(v.Type()); isEnum {
+		if !enumContains(enum, v) {
+			return invalidEnumValueError(enum, v)
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			err := checkEnum(callbacks, v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkBodyEnums walks the decoded body and reports every invalid enum value,
+// the zero values of the optional fields are considered omitted
+func checkBodyEnums(callbacks shared.ChipiCallbacks, path string, v reflect.Value, parsingErrors map[string]string, depth int) {
+	// protect against recursive structures
+	if depth > maxEnumCheckDepth {
+		return
+	}
+
+	for (v.Kind() == reflect.Ptr) || (v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if isEnum, enum := callbacks.EnumResolver(v.Type()); isEnum {
+		if !enumContains(enum, v) {
+			parsingErrors[path] = invalidEnumValueError(enum, v).Error()
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			tag := schema.ParseJsonTag(f)
+			if !f.IsExported() || tag.GetIgnored() {
+				continue
+			}
+
+			field := v.Field(i)
+			if !tag.GetRequired() && field.IsZero() {
+				continue
+			}
+
+			fieldPath := path + "." + tag.Name
+			// embedded fields are flattened
+			if f.Anonymous {
+				fieldPath = path
+			}
+
+			checkBodyEnums(callbacks, fieldPath, field, parsingErrors, depth+1)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			checkBodyEnums(callbacks, path+"["+strconv.Itoa(i)+"]", v.Index(i), parsingErrors, depth+1)
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			checkBodyEnums(callbacks, fmt.Sprintf("%s.%v", path, iter.Key().Interface()), iter.Value(), parsingErrors, depth+1)
+		}
+	}
+}
+
+const maxEnumCheckDepth = 32
+
+// CheckEnumTypes returns an error if one of the enums used by the request
+// sections (Path, Query, Header and Body) has a type which cannot be compared
+func CheckEnumTypes(callbacks shared.ChipiCallbacks, reqObject interface{}) error {
+	typ := reflect.TypeOf(reqObject).Elem()
+	visited := map[reflect.Type]bool{}
+
+	for _, section := range []string{"Path", "Query", "Header", "Body"} {
+		f, found := typ.FieldByName(section)
+		if !found {
+			continue
+		}
+
+		err := checkEnumType(callbacks, f.Type, visited)
+		if err != nil {
+			return fmt.Errorf("%s: %w", section, err)
+		}
+	}
+
+	return nil
+}
+
+func checkEnumType(callbacks shared.ChipiCallbacks, t reflect.Type, visited map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// protect against recursive structures
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	if isEnum, _ := callbacks.EnumResolver(t); isEnum {
+		if !t.Comparable() {
+			return fmt.Errorf("enum type %v is not comparable", t)
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || schema.ParseJsonTag(f).GetIgnored() {
+				continue
+			}
+
+			err := checkEnumType(callbacks, f.Type, visited)
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return checkEnumType(callbacks, t.Elem(), visited)
+	}
+
+	return nil
+}
+
+func enumContains(enum shared.Enum, v reflect.Value) bool {
+	if !v.Comparable() {
+		return false
+	}
+
+	for _, entry := range enum {
+		ev := reflect.ValueOf(entry.Value)
+		if !ev.IsValid() {
+			continue
+		}
+
+		// values may be declared with their underlying type (ex: 2 instead of Status(2))
+		if ev.Type() != v.Type() {
+			if !sameKindFamily(ev.Kind(), v.Kind()) || !ev.CanConvert(v.Type()) {
+				continue
+			}
+
+			// the conversion must be exact (ex: 1.5 is not 1)
+			converted := ev.Convert(v.Type())
+			if !converted.Convert(ev.Type()).Equal(ev) {
+				continue
+			}
+			ev = converted
+		}
+
+		if ev.Comparable() && ev.Equal(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sameKindFamily(a reflect.Kind, b reflect.Kind) bool {
+	return kindFamily(a) == kindFamily(b)
+}
+
+func kindFamily(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "bool"
+	default:
+		return k.String()
+	}
+}
+
+func invalidEnumValueError(enum shared.Enum, v reflect.Value) error {
+	values := []string{}
+	for _, entry := range enum {
+		values = append(values, fmt.Sprintf("%v", entry.Value))
+	}
+
+	return fmt.Errorf("invalid value %v, expected one of: %s", v.Interface(), strings.Join(values, ", "))
+}
@@ -0,0 +1,58 @@
+package wrapper
+
+import (
+	"io"
+	"reflect"
+)
+
+var (
+	_readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+)
+
+// closeBodyFiles closes the files received as io.Reader (or []io.Reader) in
+// the body of obj, the handlers only read them.
+func closeBodyFiles(obj reflect.Value) {
+	if !obj.IsValid() || obj.IsNil() {
+		return
+	}
+
+	body := obj.Elem().FieldByName("Body")
+	for body.Kind() == reflect.Ptr {
+		if body.IsNil() {
+			return
+		}
+		body = body.Elem()
+	}
+
+	if body.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, f := range reflect.VisibleFields(body.Type()) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+
+		fv, err := body.FieldByIndexErr(f.Index)
+		if err != nil {
+			// nil embedded pointer
+			continue
+		}
+
+		switch {
+		case f.Type == _readerType:
+			closeReader(fv)
+
+		case (f.Type.Kind() == reflect.Slice) && (f.Type.Elem() == _readerType):
+			for i := 0; i < fv.Len(); i++ {
+				closeReader(fv.Index(i))
+			}
+		}
+	}
+}
+
+func closeReader(v reflect.Value) {
+	if closer, ok := v.Interface().(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
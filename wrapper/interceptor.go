@@ -0,0 +1,84 @@
+package wrapper
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+)
+
+// Call is the request being handled, as seen by the interceptors
+type Call struct {
+	Request *http.Request
+	Writer  http.ResponseWriter
+
+	// the filled request object, a pointer to a copy of the structure
+	// given to the builder
+	Object interface{}
+
+	response reflect.Value
+}
+
+// Response returns a pointer to the Response field of the request object,
+// nil if there is none. The value is encoded once the chain returns.
+func (c *Call) Response() interface{} {
+	if !c.response.IsValid() {
+		return nil
+	}
+
+	return c.response.Addr().Interface()
+}
+
+// HandleFunc calls the next interceptor or, for the last one, Handle
+type HandleFunc func(ctx context.Context, call *Call) error
+
+// Interceptor runs around Handle once the request is decoded, it can:
+//   - stop the request by returning an error without calling next
+//   - give a decorated context to next
+//   - change the response value after next returned
+type Interceptor func(ctx context.Context, call *Call, next HandleFunc) error
+
+// Intercept returns an interceptor only called for requests of type T, the
+// other requests go straight to next.
+//
+//	api.Use(wrapper.Intercept(func(ctx context.Context, req *GetPetRequest, call *wrapper.Call, next wrapper.HandleFunc) error {
+//		...
+//	}))
+func Intercept[T any](fn func(ctx context.Context, req *T, call *Call, next HandleFunc) error) Interceptor {
+	return func(ctx context.Context, call *Call, next HandleFunc) error {
+		req, ok := call.Object.(*T)
+		if !ok {
+			return next(ctx, call)
+		}
+
+		return fn(ctx, req, call, next)
+	}
+}
+
+// chainInterceptors returns a HandleFunc calling the interceptors in order then handle
+func chainInterceptors(handle HandleFunc, interceptors ...[]Interceptor) HandleFunc {
+	all := []Interceptor{}
+	for _, list := range interceptors {
+		all = append(all, list...)
+	}
+
+	for i := len(all) - 1; i >= 0; i-- {
+		interceptor := all[i]
+		next := handle
+		handle = func(ctx context.Context, call *Call) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+
+	return handle
+}
+
+// callHandler is the end of the chain
+func callHandler(ctx context.Context, call *Call) error {
+	if rr, ok := call.Object.(HandlerWithRequestInterface); ok {
+		return rr.Handle(ctx, call.Request, call.Writer)
+	} else if rr, ok := call.Object.(HandlerInterface); ok {
+		return rr.Handle(ctx, call.Writer)
+	}
+
+	return nil
+}
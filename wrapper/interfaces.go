@@ -11,11 +11,36 @@ type BodyDecoder interface {
 	DecodeBody(body io.ReadCloser, target interface{}, obj interface{}) error
 }
 
+// BodyDecoderWithRequest is used instead of BodyDecoder when the decoder
+// needs the request itself (ex: multipart bodies)
+type BodyDecoderWithRequest interface {
+	DecodeRequestBody(r *http.Request, target interface{}, obj interface{}) error
+}
+
+// BodyExtensionsInterface lets a decoder document its options
+// in the specification (ex: x-max-depth)
+type BodyExtensionsInterface interface {
+	BodyExtensions() map[string]interface{}
+}
+
+// BodyDecodersInterface lets an operation accept several content types, the
+// decoders are either a BodyDecoder or a BodyDecoderWithRequest and are
+// selected with the request Content-Type.
+type BodyDecodersInterface interface {
+	BodyDecoders() map[string]interface{}
+}
+
 // ResponseEncoder is required for structures with a `Response` field
 type ResponseEncoder interface {
 	EncodeResponse(ctx context.Context, out http.ResponseWriter, obj interface{})
 }
 
+// ResponseEncodersInterface lets an operation produce several content types,
+// the encoder is selected with the Accept header of the request.
+type ResponseEncodersInterface interface {
+	ResponseEncoders() map[string]ResponseEncoder
+}
+
 type HandlerInterface interface {
 	Handle(context.Context, http.ResponseWriter) error
 }
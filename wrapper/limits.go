@@ -0,0 +1,72 @@
+package wrapper
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/schmurfy/chipi/shared"
+)
+
+// ErrBodyTooLarge is returned when the request body exceeds its maximum size
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// MaxBodySize returns the maximum size of the body of obj, it is set with the max-size
+// tag of the Body field (ex: `max-size:"1MB"`) and defaults to defaultSize (0 means no limit)
+func MaxBodySize(obj interface{}, defaultSize int64) (int64, error) {
+	typ := reflect.TypeOf(obj)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return defaultSize, nil
+	}
+
+	bodyField, found := typ.FieldByName("Body")
+	if !found {
+		return defaultSize, nil
+	}
+
+	maxSize, found := bodyField.Tag.Lookup("max-size")
+	if !found {
+		return defaultSize, nil
+	}
+
+	return shared.ParseSize(maxSize)
+}
+
+// limitedBody remembers if the limit was reached since the
+// decoders may not return the original error
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func newLimitedBody(w http.ResponseWriter, r *http.Request, limit int64) *limitedBody {
+	return &limitedBody{
+		ReadCloser: http.MaxBytesReader(w, r.Body, limit),
+	}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+
+	return n, err
+}
+
+func isBodyTooLarge(r *http.Request, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return true
+	}
+
+	body, ok := r.Body.(*limitedBody)
+	return ok && body.exceeded
+}
@@ -0,0 +1,74 @@
+package wrapper
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type loggerKey struct{}
+
+// LoggerFromContext returns the logger of the request, it includes the operation
+// id, route and method. slog.Default() is returned when the builder has no logger.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// ContextWithLogger returns a copy of ctx holding logger, useful to
+// test handlers or to add attributes from an interceptor
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// requestLogger returns the logger of a request
+func requestLogger(ctx context.Context, logger *slog.Logger, r *http.Request, obj interface{}) *slog.Logger {
+	args := []any{
+		slog.String("operation_id", OperationID(obj)),
+		slog.String("method", r.Method),
+	}
+
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		args = append(args, slog.String("route", rctx.RoutePattern()))
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		args = append(args, slog.String("trace_id", sc.TraceID().String()))
+	}
+
+	return logger.With(args...)
+}
+
+// failure is what went wrong with a request, logged once it is done
+type failure struct {
+	message string
+	err     error
+	args    []any
+}
+
+// logFailure logs a failed request, as an error for 5xx statuses and
+// as a warning otherwise
+func logFailure(ctx context.Context, f *failure, status int, duration time.Duration) {
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	args := []any{
+		slog.Int("status", status),
+		slog.Duration("duration", duration),
+	}
+	if f.err != nil {
+		args = append(args, slog.Any("error", f.err))
+	}
+	args = append(args, f.args...)
+
+	LoggerFromContext(ctx).Log(ctx, level, f.message, args...)
+}
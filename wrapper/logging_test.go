@@ -0,0 +1,169 @@
+package wrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/request"
+	"github.com/schmurfy/chipi/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loggedRequest struct {
+	response.ErrorEncoder
+	request.JsonBodyDecoder
+
+	Path struct {
+		Id int
+	}
+
+	Body struct {
+		Name string
+	}
+}
+
+func (r *loggedRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	switch r.Body.Name {
+	case "error":
+		return errors.New("broken")
+	case "panic":
+		panic("oops")
+	}
+
+	LoggerFromContext(ctx).Info("handled", "name", r.Body.Name)
+	return nil
+}
+
+func TestLogging(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Logging", func() {
+		var out *bytes.Buffer
+		var router *chi.Mux
+
+		g.BeforeEach(func() {
+			out = &bytes.Buffer{}
+
+			config := NewConfig()
+			config.Logger = slog.New(slog.NewJSONHandler(out, nil))
+
+			router = chi.NewRouter()
+			router.Post("/users/{Id}", WrapRequestWithConfig(&loggedRequest{}, config))
+		})
+
+		call := func(path string, body string) []map[string]interface{} {
+			r := httptest.NewRequest("POST", path, strings.NewReader(body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			ret := []map[string]interface{}{}
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				if line == "" {
+					continue
+				}
+
+				entry := map[string]interface{}{}
+				require.NoError(g, json.Unmarshal([]byte(line), &entry))
+				ret = append(ret, entry)
+			}
+
+			return ret
+		}
+
+		g.It("should give the request logger to the handler", func() {
+			entries := call("/users/42", `{"Name": "john"}`)
+			require.Len(g, entries, 1)
+
+			assert.Equal(g, "handled", entries[0]["msg"])
+			assert.Equal(g, "john", entries[0]["name"])
+			assert.Equal(g, "loggedRequest", entries[0]["operation_id"])
+			assert.Equal(g, "/users/{Id}", entries[0]["route"])
+			assert.Equal(g, "POST", entries[0]["method"])
+		})
+
+		g.It("should give the request logger to the interceptors", func() {
+			config := NewConfig()
+			config.Logger = slog.New(slog.NewJSONHandler(out, nil))
+			interceptor := func(ctx context.Context, call *Call, next HandleFunc) error {
+				LoggerFromContext(call.Request.Context()).Info("intercepted")
+				return next(ctx, call)
+			}
+			router.Post("/intercepted/{Id}", WrapRequestWithConfig(&loggedRequest{}, config, interceptor))
+
+			entries := call("/intercepted/42", `{"Name": "john"}`)
+			require.Len(g, entries, 2)
+
+			assert.Equal(g, "intercepted", entries[0]["msg"])
+			assert.Equal(g, "loggedRequest", entries[0]["operation_id"])
+		})
+
+		g.It("should log decoding failures", func() {
+			entries := call("/users/abc", `{"Name": "john"}`)
+			require.Len(g, entries, 1)
+
+			assert.Equal(g, "WARN", entries[0]["level"])
+			assert.Equal(g, "request decoding failed", entries[0]["msg"])
+			assert.Equal(g, float64(http.StatusBadRequest), entries[0]["status"])
+			assert.Equal(g, "loggedRequest", entries[0]["operation_id"])
+			assert.Contains(g, entries[0], "duration")
+			assert.Equal(g, []interface{}{"request.path.Id"}, entries[0]["fields"])
+		})
+
+		g.It("should not log the values of the decoding failures", func() {
+			entries := call("/users/s3cr3t", `{"Name": "john"}`)
+			require.Len(g, entries, 1)
+
+			assert.Equal(g, "request decoding failed", entries[0]["msg"])
+			assert.NotContains(g, out.String(), "s3cr3t")
+		})
+
+		g.It("should log the unacceptable responses", func() {
+			config := NewConfig()
+			config.Logger = slog.New(slog.NewJSONHandler(out, nil))
+			router.Get("/items", WrapRequestWithConfig(&negotiatedRequest{}, config))
+
+			r := httptest.NewRequest("GET", "/items", nil)
+			r.Header.Set("Accept", "application/msgpack")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			require.Equal(g, http.StatusNotAcceptable, w.Code)
+
+			entry := map[string]interface{}{}
+			require.NoError(g, json.Unmarshal(out.Bytes(), &entry))
+			assert.Equal(g, "WARN", entry["level"])
+			assert.Equal(g, "no acceptable response encoder", entry["msg"])
+			assert.Equal(g, float64(http.StatusNotAcceptable), entry["status"])
+		})
+
+		g.It("should log errors", func() {
+			entries := call("/users/42", `{"Name": "error"}`)
+			require.Len(g, entries, 1)
+
+			assert.Equal(g, "WARN", entries[0]["level"])
+			assert.Equal(g, "request failed", entries[0]["msg"])
+			assert.Equal(g, "broken", entries[0]["error"])
+			assert.Equal(g, float64(http.StatusBadRequest), entries[0]["status"])
+		})
+
+		g.It("should log panics", func() {
+			entries := call("/users/42", `{"Name": "panic"}`)
+			require.Len(g, entries, 1)
+
+			assert.Equal(g, "ERROR", entries[0]["level"])
+			assert.Equal(g, "request panicked", entries[0]["msg"])
+			assert.Equal(g, "panic: oops", entries[0]["error"])
+			assert.Equal(g, float64(http.StatusInternalServerError), entries[0]["status"])
+			assert.Contains(g, entries[0]["stack"], "loggedRequest")
+		})
+	})
+}
@@ -0,0 +1,91 @@
+package wrapper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PanicError is given to the error handler when the decoding or the
+// handling of a request panics
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func (e *PanicError) StatusCode() int {
+	return http.StatusInternalServerError
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+// recoverPanic must be deferred, it records the panic on the span, gives it to
+// onPanic and sends it to the error handler of requestObject() (or panics again
+// if asked to), nothing is sent if the response was already started.
+func recoverPanic(ctx context.Context, w *ResponseRecorder, requestObject func() interface{}, config *Config, onPanic func(*PanicError)) {
+	value := recover()
+	if value == nil {
+		return
+	}
+
+	// used to abort the response on purpose
+	if value == http.ErrAbortHandler {
+		panic(value)
+	}
+
+	err := &PanicError{
+		Value: value,
+		Stack: debug.Stack(),
+	}
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithAttributes(
+		attribute.String("exception.stacktrace", string(err.Stack)),
+	))
+	span.SetStatus(codes.Error, err.Error())
+
+	onPanic(err)
+
+	if config.Repanic {
+		panic(value)
+	}
+
+	// the headers were sent, the client will see a truncated response
+	if w.Started() {
+		return
+	}
+
+	handleError(ctx, w, requestObject(), config, err)
+}
+
+// handleError sends err to the error handler of the request, or the default
+// one if it has none
+func handleError(ctx context.Context, w http.ResponseWriter, obj interface{}, config *Config, err error) {
+	if rr, ok := obj.(ErrorHandlerInterface); ok {
+		rr.HandleError(ctx, w, err)
+		return
+	}
+
+	if config.ErrorHandler != nil {
+		config.ErrorHandler.HandleError(ctx, w, err)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
@@ -0,0 +1,119 @@
+package wrapper
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder remembers the status sent to the client and can keep a copy
+// of the body, the other features of the writer (flush, hijack...) are kept.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+
+	// the copy of the body, nil if not captured
+	body      *bytes.Buffer
+	maxBody   int
+	truncated bool
+}
+
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w}
+}
+
+// CaptureBody keeps a copy of the body written from now on, the copy is
+// dropped if it grows larger than maxSize bytes
+func (w *ResponseRecorder) CaptureBody(maxSize int) {
+	w.body = &bytes.Buffer{}
+	w.maxBody = maxSize
+}
+
+// Body returns the captured body, false if it was too large (or not captured)
+func (w *ResponseRecorder) Body() ([]byte, bool) {
+	if (w.body == nil) || w.truncated {
+		return nil, false
+	}
+
+	return w.body.Bytes(), true
+}
+
+func (w *ResponseRecorder) WriteHeader(status int) {
+	// informational responses are followed by the real one
+	if (w.status == 0) && (status >= http.StatusOK) {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *ResponseRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if (w.body != nil) && !w.truncated {
+		if w.body.Len()+len(data) > w.maxBody {
+			w.truncated = true
+			w.body.Reset()
+		} else {
+			w.body.Write(data)
+		}
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// FlushError is used by http.ResponseController
+func (w *ResponseRecorder) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *ResponseRecorder) Flush() {
+	_ = w.FlushError()
+}
+
+// Hijack is used by the websockets, the status is 101 once hijacked
+func (w *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if (err == nil) && (w.status == 0) {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+// ReadFrom keeps the optimizations of the writer (ex: sendfile with http.ServeContent)
+func (w *ResponseRecorder) ReadFrom(r io.Reader) (int64, error) {
+	// the copy goes through Write
+	if w.body != nil {
+		return io.Copy(struct{ io.Writer }{w}, r)
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// Unwrap gives access to the other features of the writer with http.ResponseController
+func (w *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Started returns true once the headers were sent
+func (w *ResponseRecorder) Started() bool {
+	return w.status != 0
+}
+
+// Status returns the status sent, 200 if nothing was sent
+func (w *ResponseRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
@@ -0,0 +1,69 @@
+package wrapper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("ResponseRecorder", func() {
+		g.It("should record the status", func() {
+			w := NewResponseRecorder(httptest.NewRecorder())
+			assert.False(g, w.Started())
+			assert.Equal(g, http.StatusOK, w.Status())
+
+			w.WriteHeader(http.StatusContinue)
+			assert.False(g, w.Started())
+
+			w.WriteHeader(http.StatusCreated)
+			assert.True(g, w.Started())
+			assert.Equal(g, http.StatusCreated, w.Status())
+		})
+
+		g.It("should not capture the body by default", func() {
+			w := NewResponseRecorder(httptest.NewRecorder())
+			_, err := w.Write([]byte("content"))
+			require.NoError(g, err)
+
+			_, complete := w.Body()
+			assert.False(g, complete)
+		})
+
+		g.It("should capture the body", func() {
+			rec := httptest.NewRecorder()
+			w := NewResponseRecorder(rec)
+			w.CaptureBody(10)
+
+			_, err := w.Write([]byte("some "))
+			require.NoError(g, err)
+			_, err = w.ReadFrom(strings.NewReader("text"))
+			require.NoError(g, err)
+
+			body, complete := w.Body()
+			assert.True(g, complete)
+			assert.Equal(g, "some text", string(body))
+			assert.Equal(g, "some text", rec.Body.String())
+		})
+
+		g.It("should drop the body larger than the limit", func() {
+			rec := httptest.NewRecorder()
+			w := NewResponseRecorder(rec)
+			w.CaptureBody(4)
+
+			_, err := w.Write([]byte("too large"))
+			require.NoError(g, err)
+
+			_, complete := w.Body()
+			assert.False(g, complete)
+			assert.Equal(g, "too large", rec.Body.String())
+		})
+	})
+}
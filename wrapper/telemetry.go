@@ -0,0 +1,107 @@
+package wrapper
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// replaces the values of the fields tagged with `chipi:"sensitive"`
+const _redacted = "[redacted]"
+
+// OperationIDKey is the attribute holding the operation id on spans and metrics
+const OperationIDKey = attribute.Key("chipi.operation_id")
+
+var _instruments = newInstruments(otel.Meter("chipi"))
+
+type instruments struct {
+	requests     metric.Int64Counter
+	duration     metric.Float64Histogram
+	decodeErrors metric.Int64Counter
+}
+
+// the global meter returns working instruments even on error
+func newInstruments(meter metric.Meter) *instruments {
+	requests, _ := meter.Int64Counter("chipi.server.requests",
+		metric.WithDescription("Number of requests handled"),
+		metric.WithUnit("{request}"),
+	)
+
+	duration, _ := meter.Float64Histogram("chipi.server.request.duration",
+		metric.WithDescription("Duration of the requests"),
+		metric.WithUnit("s"),
+	)
+
+	decodeErrors, _ := meter.Int64Counter("chipi.server.decode_errors",
+		metric.WithDescription("Number of requests rejected while decoding"),
+		metric.WithUnit("{request}"),
+	)
+
+	return &instruments{
+		requests:     requests,
+		duration:     duration,
+		decodeErrors: decodeErrors,
+	}
+}
+
+// OperationID returns the id of the operation handled by obj, the name
+// of its type
+func OperationID(obj interface{}) string {
+	typ := reflect.TypeOf(obj)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	return typ.Name()
+}
+
+// operationAttributes returns the attributes shared by the spans and metrics of an operation
+func operationAttributes(r *http.Request, obj interface{}) []attribute.KeyValue {
+	ret := []attribute.KeyValue{
+		OperationIDKey.String(OperationID(obj)),
+		semconv.HTTPRequestMethodKey.String(r.Method),
+	}
+
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			ret = append(ret, semconv.HTTPRoute(pattern))
+		}
+	}
+
+	return ret
+}
+
+// recordRequest ends the operation span and records the metrics
+func recordRequest(ctx context.Context, span trace.Span, attrs []attribute.KeyValue, status int, started time.Time) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+
+	attrs = append(attrs, semconv.HTTPResponseStatusCode(status))
+	_instruments.requests.Add(ctx, 1, metric.WithAttributes(attrs...))
+	_instruments.duration.Record(ctx, time.Since(started).Seconds(), metric.WithAttributes(attrs...))
+}
+
+// inPhase runs fn in a child span of the operation (decode, handle or encode)
+func inPhase(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, span := _tracer.Start(ctx, name)
+	defer span.End()
+
+	err := fn(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,297 @@
+package wrapper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/franela/goblin"
+	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/request"
+	"github.com/schmurfy/chipi/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+type updateUserRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+	request.JsonBodyDecoder
+
+	Path struct {
+		Id int
+	}
+
+	Header struct {
+		ApiKey string `chipi:"sensitive"`
+	}
+
+	Body struct {
+		Name string
+	}
+
+	Response struct {
+		Id int
+	}
+}
+
+func (r *updateUserRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	r.Response.Id = r.Path.Id
+	return nil
+}
+
+// records the measurements made by the wrapper
+type measurement struct {
+	name       string
+	value      float64
+	attributes attribute.Set
+}
+
+type recordingMeter struct {
+	noop.Meter
+
+	lock         sync.Mutex
+	measurements []measurement
+}
+
+func (m *recordingMeter) record(name string, value float64, attributes attribute.Set) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.measurements = append(m.measurements, measurement{name: name, value: value, attributes: attributes})
+}
+
+// find returns the measurements of the instrument name
+func (m *recordingMeter) find(name string) []measurement {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ret := []measurement{}
+	for _, measure := range m.measurements {
+		if measure.name == name {
+			ret = append(ret, measure)
+		}
+	}
+
+	return ret
+}
+
+func (m *recordingMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &recordingCounter{name: name, meter: m}, nil
+}
+
+func (m *recordingMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return &recordingHistogram{name: name, meter: m}, nil
+}
+
+type recordingCounter struct {
+	noop.Int64Counter
+
+	name  string
+	meter *recordingMeter
+}
+
+func (c *recordingCounter) Add(ctx context.Context, incr int64, options ...metric.AddOption) {
+	c.meter.record(c.name, float64(incr), metric.NewAddConfig(options).Attributes())
+}
+
+type recordingHistogram struct {
+	noop.Float64Histogram
+
+	name  string
+	meter *recordingMeter
+}
+
+func (h *recordingHistogram) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	h.meter.record(h.name, value, metric.NewRecordConfig(options).Attributes())
+}
+
+type recordingMeterProvider struct {
+	embedded.MeterProvider
+	meter *recordingMeter
+}
+
+func (p *recordingMeterProvider) Meter(name string, options ...metric.MeterOption) metric.Meter {
+	return p.meter
+}
+
+var (
+	_recordingMeter     = &recordingMeter{}
+	_recordingMeterOnce sync.Once
+)
+
+// like the tracer, the instruments are bound to the first global provider set
+func useRecordingMeter() *recordingMeter {
+	_recordingMeterOnce.Do(func() {
+		otel.SetMeterProvider(&recordingMeterProvider{meter: _recordingMeter})
+	})
+
+	_recordingMeter.lock.Lock()
+	defer _recordingMeter.lock.Unlock()
+
+	_recordingMeter.measurements = nil
+	return _recordingMeter
+}
+
+// a connection upgraded by the handler (ex: websocket)
+type hijackRequest struct {
+	response.ErrorEncoder
+
+	Path struct{}
+}
+
+func (r *hijackRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return errors.New("not a hijacker")
+	}
+
+	_, _, err := hijacker.Hijack()
+	return err
+}
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (w *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+func TestTelemetry(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	g.Describe("Telemetry", func() {
+		var tracer *recordingTracer
+		var meter *recordingMeter
+		var router *chi.Mux
+
+		g.BeforeEach(func() {
+			tracer = useRecordingTracer()
+			meter = useRecordingMeter()
+
+			router = chi.NewRouter()
+			router.Patch("/users/{Id}", WrapRequest(&updateUserRequest{}))
+		})
+
+		call := func(path string, body string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest("PATCH", path, strings.NewReader(body))
+			r.Header.Set("ApiKey", "secret")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			return w
+		}
+
+		g.It("should name the span after the operation", func() {
+			w := call("/users/42", `{"Name": "john"}`)
+			require.Equal(g, http.StatusOK, w.Code)
+
+			span := tracer.span("updateUserRequest")
+			require.NotNil(g, span)
+
+			route, found := span.attribute("http.route")
+			require.True(g, found)
+			assert.Equal(g, "/users/{Id}", route.AsString())
+
+			status, found := span.attribute("http.response.status_code")
+			require.True(g, found)
+			assert.Equal(g, int64(200), status.AsInt64())
+
+			for _, phase := range []string{"decode", "handle", "encode"} {
+				assert.NotNil(g, tracer.span(phase), phase)
+			}
+		})
+
+		g.It("should redact sensitive values", func() {
+			w := call("/users/42", `{"Name": "john"}`)
+			require.Equal(g, http.StatusOK, w.Code)
+
+			decode := tracer.span("decode")
+			require.NotNil(g, decode)
+
+			id, found := decode.attribute("request.path.Id")
+			require.True(g, found)
+			assert.Equal(g, "42", id.AsString())
+
+			apiKey, found := decode.attribute("request.header.ApiKey")
+			require.True(g, found)
+			assert.Equal(g, "[redacted]", apiKey.AsString())
+		})
+
+		g.It("should record the requests", func() {
+			w := call("/users/42", `{"Name": "john"}`)
+			require.Equal(g, http.StatusOK, w.Code)
+
+			requests := meter.find("chipi.server.requests")
+			require.Len(g, requests, 1)
+
+			operation, _ := requests[0].attributes.Value("chipi.operation_id")
+			assert.Equal(g, "updateUserRequest", operation.AsString())
+
+			route, _ := requests[0].attributes.Value("http.route")
+			assert.Equal(g, "/users/{Id}", route.AsString())
+
+			status, _ := requests[0].attributes.Value("http.response.status_code")
+			assert.Equal(g, int64(200), status.AsInt64())
+
+			assert.Len(g, meter.find("chipi.server.request.duration"), 1)
+			assert.Empty(g, meter.find("chipi.server.decode_errors"))
+		})
+
+		g.It("should let the handlers hijack the connection", func() {
+			router.Get("/ws", WrapRequest(&hijackRequest{}))
+
+			w := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/ws", nil))
+			assert.True(g, w.hijacked)
+
+			requests := meter.find("chipi.server.requests")
+			require.Len(g, requests, 1)
+
+			status, _ := requests[0].attributes.Value("http.response.status_code")
+			assert.Equal(g, int64(http.StatusSwitchingProtocols), status.AsInt64())
+		})
+
+		g.It("should keep the io.ReaderFrom optimization", func() {
+			w := httptest.NewRecorder()
+			recorder := NewResponseRecorder(w)
+
+			var writer http.ResponseWriter = recorder
+			readerFrom, ok := writer.(io.ReaderFrom)
+			require.True(g, ok)
+
+			_, err := readerFrom.ReadFrom(strings.NewReader("content"))
+			require.NoError(g, err)
+			assert.Equal(g, http.StatusOK, recorder.Status())
+			assert.Equal(g, "content", w.Body.String())
+		})
+
+		g.It("should count the decode errors", func() {
+			w := call("/users/abc", `{"Name": "john"}`)
+			require.Equal(g, http.StatusBadRequest, w.Code)
+
+			assert.Len(g, meter.find("chipi.server.decode_errors"), 1)
+
+			requests := meter.find("chipi.server.requests")
+			require.Len(g, requests, 1)
+
+			status, _ := requests[0].attributes.Value("http.response.status_code")
+			assert.Equal(g, int64(400), status.AsInt64())
+		})
+	})
+}
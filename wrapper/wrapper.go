@@ -5,123 +5,55 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
 	"reflect"
-	"strconv"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/schmurfy/chipi/schema"
 	"github.com/schmurfy/chipi/shared"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	_tracer  = otel.Tracer("chipi")
-	_noValue = reflect.Value{}
+	_tracer = otel.Tracer("chipi")
 )
 
-func convertValue(fieldType reflect.Type, value string) (reflect.Value, error) {
-	switch fieldType.Kind() {
-	case reflect.Ptr:
-		fieldType := fieldType.Elem()
-		setValue, err := convertValue(fieldType, value)
-		if err != nil {
-			return _noValue, err
-		}
-		setValuePtr := reflect.New(fieldType)
-		setValuePtr.Elem().Set(setValue)
-		return setValuePtr, nil
-	case reflect.Map:
-		outMapPtr := reflect.New(fieldType)
-		err := json.Unmarshal([]byte(value), outMapPtr.Interface())
-		if err != nil {
-			return _noValue, err
-		}
-		return outMapPtr.Elem(), nil
-	case reflect.Slice:
-		param := strings.Split(
-			strings.Trim(value, `[]`),
-			",")
-		sliceType := fieldType.Elem()
-		setValue := reflect.New(reflect.SliceOf(sliceType)).Elem()
-		for _, v := range param {
-			vv, err := convertValue(sliceType, strings.TrimSpace(v))
-			if err != nil {
-				return _noValue, err
-			}
-			setValue = reflect.Append(setValue, vv)
-		}
-		return setValue, nil
+func setFValue(f reflect.Value, value string) error {
+	v, err := shared.ConvertValue(f.Type(), value)
 
-	case reflect.Struct:
-		setValue := reflect.New(fieldType)
-		iface := setValue.Interface()
-		err := json.Unmarshal([]byte(value), &iface)
-		if err != nil {
-			return _noValue, err
-		}
-		return setValue.Elem(), nil
-
-	case reflect.String:
-		trimed := value
-		if len(trimed) > 2 && trimed[0] == '"' && trimed[len(trimed)-1] == '"' {
-			trimed = trimed[1 : len(trimed)-1] // remove quotes only if they are at the beginning AND the end
-		}
-		return reflect.ValueOf(trimed).Convert(fieldType), nil
-
-	case reflect.Bool:
-		setValue, err := strconv.ParseBool(value)
-		if err != nil {
-			return _noValue, err
-		}
-		return reflect.ValueOf(setValue).Convert(fieldType), nil
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		n, err := strconv.ParseInt(value, 10, 64)
-		if err != nil {
-			return _noValue, err
-		}
-		setValue := reflect.ValueOf(n).Convert(fieldType)
-		return setValue, nil
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		n, err := strconv.ParseUint(value, 10, 64)
-		if err != nil {
-			return _noValue, err
-		}
-		setValue := reflect.ValueOf(n).Convert(fieldType)
-		return setValue, nil
-
-	case reflect.Float32, reflect.Float64:
-		x, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			return _noValue, err
-		}
-		setValue := reflect.ValueOf(x).Convert(fieldType)
-		return setValue, nil
-
-	default:
-		return reflect.Value{}, fmt.Errorf("invalid type: %v", fieldType.Kind())
+	if err != nil {
+		return err
 	}
-}
 
-func setFValue(ctx context.Context, path string, f reflect.Value, value string) error {
-	v, err := convertValue(f.Type(), value)
+	f.Set(v)
+	return nil
+}
 
+// setParam sets the field value, records it on the span (unless the field is
+// tagged as sensitive) and checks it against its enum if any
+func setParam(ctx context.Context, config *Config, path string, field reflect.StructField, f reflect.Value, value string) error {
+	err := setFValue(f, value)
 	if err != nil {
 		return err
 	}
 
-	f.Set(v)
+	traced := value
+	if schema.ParseJsonTag(field).GetSensitive() {
+		traced = _redacted
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String(path, traced))
 
-	trace.SpanFromContext(ctx).SetAttributes(attribute.String(path, value))
-	return nil
+	return checkEnum(config.Callbacks, f)
 }
 
-func createFilledRequestObject(r *http.Request, obj interface{}, parsingErrors map[string]string) (ret reflect.Value, response reflect.Value, err error) {
+func createFilledRequestObject(r *http.Request, obj interface{}, config *Config, parsingErrors map[string]string) (ret reflect.Value, response reflect.Value, err error) {
 	typ := reflect.TypeOf(obj)
 
 	if typ.Kind() == reflect.Ptr {
@@ -147,12 +79,13 @@ func createFilledRequestObject(r *http.Request, obj interface{}, parsingErrors m
 	pathValue := ret.Elem().FieldByName("Path")
 	rctx := chi.RouteContext(r.Context())
 	for _, k := range rctx.URLParams.Keys {
-		fieldValue := pathValue.FieldByName(k)
-		if fieldValue.IsValid() {
+		structField, found := pathValue.Type().FieldByName(k)
+		if found {
 			path := "request.path." + k
-			err = setFValue(ctx,
+			err = setParam(ctx, config,
 				path,
-				fieldValue,
+				structField,
+				pathValue.FieldByIndex(structField.Index),
 				rctx.URLParam(k),
 			)
 			if err != nil {
@@ -167,17 +100,25 @@ func createFilledRequestObject(r *http.Request, obj interface{}, parsingErrors m
 	if queryValue.IsValid() {
 		for _, structField := range reflect.VisibleFields(queryValue.Type()) {
 			// Tag "json" overwrite the key
-			parsedQueryFieldName := schema.ParseJsonTag(structField).Name
+			parsedTag := schema.ParseJsonTag(structField)
+			parsedQueryFieldName := parsedTag.Name
 			if parsedQueryFieldName == structField.Name {
 				parsedQueryFieldName = shared.ToSnakeCase(structField.Name)
 			}
 			path := "request.query." + parsedQueryFieldName
 
 			if value, ok := r.URL.Query()[parsedQueryFieldName]; ok {
-				err = setFValue(ctx,
+				v := value[0]
+				// `json:",string"` fields may be sent quoted
+				if parsedTag.GetAsString() {
+					v = shared.TrimQuotes(v)
+				}
+
+				err = setParam(ctx, config,
 					path,
+					structField,
 					queryValue.FieldByIndex(structField.Index),
-					value[0],
+					v,
 				)
 				if err != nil {
 					parsingErrors[path] = err.Error()
@@ -203,8 +144,9 @@ func createFilledRequestObject(r *http.Request, obj interface{}, parsingErrors m
 			}
 			path := "request.header." + attributeName
 			if r.Header.Get(headerName) != "" {
-				err = setFValue(ctx,
+				err = setParam(ctx, config,
 					path,
+					structField,
 					headerValue.Field(i),
 					r.Header.Get(headerName),
 				)
@@ -235,20 +177,38 @@ func createFilledRequestObject(r *http.Request, obj interface{}, parsingErrors m
 
 		path := "request.body"
 		// call the request method if it implements a custom decoder
-		if decoder, ok := ret.Interface().(BodyDecoder); ok {
-			err = decoder.DecodeBody(r.Body, bodyObject, ret)
-			if err != nil {
-				parsingErrors[path] = err.Error()
-				return
+		if decoders, ok := ret.Interface().(BodyDecodersInterface); ok {
+			// an empty body without content type is left empty
+			if (r.Header.Get("Content-Type") != "") || (r.ContentLength != 0) {
+				var decoder interface{}
+				decoder, err = selectBodyDecoder(r, decoders.BodyDecoders())
+				if err == nil {
+					err = decodeBody(decoder, r, bodyObject, ret)
+				}
 			}
+		} else if IsBodyDecoder(ret.Interface()) {
+			err = decodeBody(ret.Interface(), r, bodyObject, ret)
+		} else if config.BodyDecoder != nil {
+			err = decodeBody(config.BodyDecoder, r, bodyObject, ret)
 		} else {
 			err = fmt.Errorf(
 				"structure %s needs to implement BodyDecoder interface",
 				typ.Name(),
 			)
-			if err != nil {
-				parsingErrors[path] = err.Error()
+		}
+
+		if err != nil {
+			if isBodyTooLarge(r, err) {
+				err = fmt.Errorf("%w: %s", ErrBodyTooLarge, err.Error())
 			}
+
+			parsingErrors[path] = err.Error()
+			return
+		}
+
+		checkBodyEnums(config.Callbacks, path, bodyValue, parsingErrors, 0)
+		if len(parsingErrors) > 0 {
+			err = errors.New("input parsing error")
 			return
 		}
 	}
@@ -259,59 +219,194 @@ func createFilledRequestObject(r *http.Request, obj interface{}, parsingErrors m
 }
 
 func WrapRequest(obj interface{}) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return WrapRequestWithConfig(obj, NewConfig())
+}
+
+// WrapRequestWithConfig is like WrapRequest but with settings shared
+// with other operations (ex: by the builder), the interceptors run after
+// the ones of the config.
+func WrapRequestWithConfig(obj interface{}, config *Config, interceptors ...Interceptor) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
 		var err error
 		var vv reflect.Value
 		var response reflect.Value
+		var failed *failure
 
-		ctx, span := _tracer.Start(r.Context(), "WrapRequest")
+		started := time.Now()
+		w := NewResponseRecorder(rw)
+
+		attrs := operationAttributes(r, obj)
+		ctx, span := _tracer.Start(r.Context(), OperationID(obj), trace.WithAttributes(attrs...))
+
+		if config.Logger != nil {
+			ctx = ContextWithLogger(ctx, requestLogger(ctx, config.Logger, r, obj))
+		}
 
 		defer func() {
 			if err != nil {
 				span.RecordError(err)
 			}
+
+			status := w.Status()
+			// nothing was sent when panicking again
+			if (failed != nil) && !w.Started() {
+				status = http.StatusInternalServerError
+			}
+
+			recordRequest(ctx, span, attrs, status, started)
 			span.End()
+
+			if (failed != nil) && (config.Logger != nil) {
+				logFailure(ctx, failed, status, time.Since(started))
+			}
 		}()
 
+		// the filled request object once decoded
+		requestObject := func() interface{} {
+			if vv.IsValid() {
+				return vv.Interface()
+			}
+			return obj
+		}
+
+		defer recoverPanic(ctx, w, requestObject, config, func(err *PanicError) {
+			failed = &failure{
+				message: "request panicked",
+				err:     err,
+				args:    []any{slog.String("stack", string(err.Stack))},
+			}
+		})
+
 		parsingErrors := map[string]string{}
 
-		vv, response, err = createFilledRequestObject(r, obj, parsingErrors)
+		maxBodySize, err := MaxBodySize(obj, config.MaxBodySize)
 		if err != nil {
-			data, err := json.Marshal(parsingErrors)
-			if err != nil {
-				data = []byte(`{}`)
-			}
-			w.Header().Set("content-type", "application/json")
-			w.Header().Set("X-Content-Type-Options", "nosniff")
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintln(w, string(data))
+			failed = &failure{message: "invalid body size", err: err}
+			WriteParsingErrors(w, http.StatusInternalServerError, map[string]string{
+				"request.body": err.Error(),
+			})
 			return
 		}
 
-		if rr, ok := vv.Interface().(HandlerWithRequestInterface); ok {
-			err = rr.Handle(ctx, r, w)
-		} else if rr, ok := vv.Interface().(HandlerInterface); ok {
-			err = rr.Handle(ctx, w)
+		if maxBodySize > 0 {
+			// no need to read it
+			if r.ContentLength > maxBodySize {
+				_instruments.decodeErrors.Add(ctx, 1, metric.WithAttributes(attrs...))
+				failed = &failure{message: "request decoding failed", err: ErrBodyTooLarge}
+				WriteParsingErrors(w, http.StatusRequestEntityTooLarge, map[string]string{
+					"request.body": fmt.Sprintf("%s: max size is %d bytes", ErrBodyTooLarge.Error(), maxBodySize),
+				})
+				return
+			}
+
+			// the original writer closes the connection when the limit is reached
+			r.Body = newLimitedBody(rw, r, maxBodySize)
 		}
 
+		err = inPhase(ctx, "decode", func(ctx context.Context) (err error) {
+			vv, response, err = createFilledRequestObject(r.WithContext(ctx), obj, config, parsingErrors)
+			return
+		})
+		// the files opened by the decoder, even if the decoding failed
+		defer closeBodyFiles(vv)
+
 		if err != nil {
-			if rr, ok := vv.Interface().(ErrorHandlerInterface); ok {
-				rr.HandleError(ctx, w, err)
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, ErrUnsupportedMediaType):
+				status = http.StatusUnsupportedMediaType
+			case errors.Is(err, ErrBodyTooLarge):
+				status = http.StatusRequestEntityTooLarge
+			}
+
+			_instruments.decodeErrors.Add(ctx, 1, metric.WithAttributes(attrs...))
+			// the messages may contain the sent values (ex: a token), only
+			// the failing fields are logged
+			failed = &failure{
+				message: "request decoding failed",
+				args:    []any{slog.Any("fields", slices.Sorted(maps.Keys(parsingErrors)))},
+			}
+			WriteParsingErrors(w, status, parsingErrors)
+			return
+		}
+
+		// choose the encoder before doing anything
+		var encoder ResponseEncoder
+		var contentType string
+		if response.IsValid() {
+			encoder, contentType, err = selectResponseEncoder(r, obj, config)
+			if err != nil {
+				failed = &failure{message: "no acceptable response encoder", err: err}
+				WriteParsingErrors(w, http.StatusNotAcceptable, map[string]string{
+					"request.header.Accept": err.Error(),
+				})
+				return
 			}
+		}
+
+		call := &Call{
+			Request:  r.WithContext(ctx),
+			Writer:   w,
+			Object:   vv.Interface(),
+			response: response,
+		}
+
+		err = inPhase(ctx, "handle", func(ctx context.Context) error {
+			return chainInterceptors(callHandler, config.Interceptors, interceptors)(ctx, call)
+		})
+
+		if err != nil {
+			failed = &failure{message: "request failed", err: err}
+			handleError(ctx, w, vv.Interface(), config, err)
 
 		} else if response.IsValid() {
 			// encode response if any
-			if encoder, ok := obj.(ResponseEncoder); ok {
-				encoder.EncodeResponse(ctx, w, response.Interface())
+			if encoder != nil {
+				if contentType != "" {
+					w.Header().Set("Content-Type", contentType)
+					w.Header().Add("Vary", "Accept")
+				}
+
+				_ = inPhase(ctx, "encode", func(ctx context.Context) error {
+					encoder.EncodeResponse(ctx, w, response.Interface())
+					return nil
+				})
 			} else {
-				err = fmt.Errorf(
+				err = internalError{fmt.Errorf(
 					"structure %s needs to implement ResponseEncoder interface",
-					vv.Type().Name(),
-				)
-				return
-
+					vv.Elem().Type().Name(),
+				)}
+				failed = &failure{message: "request failed", err: err}
+				handleError(ctx, w, vv.Interface(), config, err)
 			}
 		}
 
 	}
 }
+
+// WriteParsingErrors sends the errors of a request by location (ex: "request.query.limit")
+// as a json object, as the operations do when the decoding fails
+func WriteParsingErrors(w http.ResponseWriter, status int, parsingErrors map[string]string) {
+	data, err := json.Marshal(parsingErrors)
+	if err != nil {
+		data = []byte(`{}`)
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	fmt.Fprintln(w, string(data))
+}
+
+// internalError is a mistake of the server, reported as a 500
+type internalError struct {
+	error
+}
+
+func (e internalError) StatusCode() int {
+	return http.StatusInternalServerError
+}
+
+func (e internalError) Unwrap() error {
+	return e.error
+}
@@ -4,21 +4,36 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"math"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/franela/goblin"
 	"github.com/go-chi/chi/v5"
+	"github.com/schmurfy/chipi/request"
 	"github.com/schmurfy/chipi/response"
+	"github.com/schmurfy/chipi/shared"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 type someData struct {
@@ -51,6 +66,433 @@ func (r *createTestUser) Handle(ctx context.Context, w http.ResponseWriter) erro
 	return encoder.Encode(r.Body)
 }
 
+type petStatus string
+
+type petKind int
+
+func (petKind) CHIPI_Enum() shared.Enum {
+	return shared.Enum{
+		{Title: "Dog", Value: 1},
+		{Title: "Cat", Value: 2},
+	}
+}
+
+type petTags []string
+
+func (petTags) CHIPI_Enum() shared.Enum {
+	return shared.Enum{{Title: "None", Value: petTags{}}}
+}
+
+type uncomparableEnumRequest struct {
+	Query struct {
+		Tags *petTags
+	}
+}
+
+type statusResolver struct{}
+
+func (*statusResolver) EnumResolver(t reflect.Type) (bool, shared.Enum) {
+	if t == reflect.TypeOf(petStatus("")) {
+		return true, shared.Enum{
+			{Title: "Available", Value: petStatus("available")},
+			{Title: "Sold", Value: "sold"},
+		}
+	}
+	return false, nil
+}
+
+type listPetsRequest struct {
+	request.JsonBodyDecoder
+	response.ErrorEncoder
+
+	Path  struct{}
+	Query struct {
+		Status *petStatus
+		Kinds  []petKind
+	}
+	Body struct {
+		Pets []struct {
+			Status petStatus `json:"status" chipi:"required"`
+			Kind   petKind   `json:"kind"`
+		} `json:"pets"`
+	}
+}
+
+func (r *listPetsRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type uploadRequest struct {
+	request.MultipartBodyDecoder
+	response.ErrorEncoder
+
+	Path struct{}
+	Body struct {
+		Name   string                  `json:"name"`
+		Tags   []string                `json:"tags"`
+		Avatar []byte                  `json:"avatar" max-size:"10B"`
+		Photos []*multipart.FileHeader `json:"photos"`
+		Resume io.Reader               `json:"resume"`
+	}
+}
+
+func (r *uploadRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	resume, err := io.ReadAll(r.Body.Resume)
+	if err != nil {
+		return err
+	}
+
+	photos := []string{}
+	for _, h := range r.Body.Photos {
+		photos = append(photos, h.Filename)
+	}
+
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":   r.Body.Name,
+		"tags":   r.Body.Tags,
+		"avatar": string(r.Body.Avatar),
+		"photos": photos,
+		"resume": string(resume),
+	})
+}
+
+type closingReader struct {
+	strings.Reader
+	closed bool
+}
+
+func (r *closingReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+type formRequest struct {
+	request.FormBodyDecoder
+	response.ErrorEncoder
+
+	Path struct{}
+	Body struct {
+		Name     string `json:"name"`
+		Age      int    `json:"age"`
+		Nickname string `json:"nickname" form:"nick"`
+	}
+}
+
+func (r *formRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return json.NewEncoder(w).Encode(r.Body)
+}
+
+type multiDecodersRequest struct {
+	response.ErrorEncoder
+
+	Path struct{}
+	Body struct {
+		Name string `json:"name"`
+	}
+}
+
+func (r *multiDecodersRequest) BodyDecoders() map[string]interface{} {
+	return map[string]interface{}{
+		"application/json":                  &request.JsonBodyDecoder{},
+		"application/x-www-form-urlencoded": &request.FormBodyDecoder{},
+	}
+}
+
+func (r *multiDecodersRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return json.NewEncoder(w).Encode(r.Body)
+}
+
+type negotiatedPet struct {
+	Name string `json:"name" xml:"name"`
+	Age  int    `json:"age" xml:"age"`
+}
+
+type negotiatedRequest struct {
+	response.ErrorEncoder
+
+	Path     struct{}
+	Response []negotiatedPet
+}
+
+func (r *negotiatedRequest) ResponseEncoders() map[string]ResponseEncoder {
+	return map[string]ResponseEncoder{
+		"application/json": &response.JsonEncoder{},
+		"application/xml":  &response.XmlEncoder{},
+		"text/csv":         &response.CsvEncoder{},
+	}
+}
+
+func (r *negotiatedRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	r.Response = []negotiatedPet{
+		{Name: "rex", Age: 3},
+		{Name: "felix", Age: 5},
+	}
+	return nil
+}
+
+type eventsRequest struct {
+	response.ErrorEncoder
+	response.SSEEncoder
+
+	Path     struct{}
+	Response response.Stream[negotiatedPet]
+
+	Producer func(ctx context.Context, send func(response.Event[negotiatedPet]) error) error
+}
+
+func (r *eventsRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	r.Response = response.NewStream(r.Producer)
+	return nil
+}
+
+type exportRequest struct {
+	response.ErrorEncoder
+
+	Path     struct{}
+	Response iter.Seq[negotiatedPet]
+
+	Count int
+}
+
+func (r *exportRequest) ResponseEncoders() map[string]ResponseEncoder {
+	return map[string]ResponseEncoder{
+		"application/json":     &response.JsonEncoder{},
+		"application/x-ndjson": &response.NDJSONEncoder{},
+		"text/csv":             &response.CsvEncoder{},
+	}
+}
+
+func (r *exportRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	r.Response = func(yield func(negotiatedPet) bool) {
+		for i := 0; i < r.Count; i++ {
+			if !yield(negotiatedPet{Name: fmt.Sprintf("pet%d", i), Age: i}) {
+				return
+			}
+		}
+	}
+	return nil
+}
+
+type limitedRequest struct {
+	request.JsonBodyDecoder
+	response.ErrorEncoder
+
+	Path struct{}
+	Body struct {
+		Name string        `json:"name"`
+		Tags []interface{} `json:"tags"`
+	} `max-size:"32B"`
+}
+
+func (r *limitedRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return json.NewEncoder(w).Encode(r.Body)
+}
+
+// records the spans created by the wrapper
+type recordedSpan struct {
+	noop.Span
+
+	lock       sync.Mutex
+	name       string
+	errors     []error
+	attributes []attribute.KeyValue
+	status     codes.Code
+}
+
+func (s *recordedSpan) RecordError(err error, options ...trace.EventOption) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.errors = append(s.errors, err)
+	config := trace.NewEventConfig(options...)
+	s.attributes = append(s.attributes, config.Attributes()...)
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attributes = append(s.attributes, kv...)
+}
+
+func (s *recordedSpan) attribute(key string) (attribute.Value, bool) {
+	for _, kv := range s.attributes {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordingTracerProvider struct {
+	embedded.TracerProvider
+	tracer *recordingTracer
+}
+
+func (p *recordingTracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return p.tracer
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	config := trace.NewSpanStartConfig(opts...)
+	span := &recordedSpan{name: name, attributes: config.Attributes()}
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// span returns the first span with the given name
+func (t *recordingTracer) span(name string) *recordedSpan {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, span := range t.spans {
+		if span.name == name {
+			return span
+		}
+	}
+
+	return nil
+}
+
+var (
+	_recordingTracer     = &recordingTracer{}
+	_recordingTracerOnce sync.Once
+)
+
+// the tracer used by the wrapper is bound to the first global provider set,
+// the same one is used by every test
+func useRecordingTracer() *recordingTracer {
+	_recordingTracerOnce.Do(func() {
+		otel.SetTracerProvider(&recordingTracerProvider{tracer: _recordingTracer})
+	})
+
+	_recordingTracer.lock.Lock()
+	defer _recordingTracer.lock.Unlock()
+
+	_recordingTracer.spans = nil
+	return _recordingTracer
+}
+
+type panicRequest struct {
+	response.ErrorEncoder
+
+	Path struct{}
+
+	Value          interface{}
+	PanicInDecoder bool
+}
+
+func (r *panicRequest) DecodeBody(body io.ReadCloser, target interface{}, obj interface{}) error {
+	if r.PanicInDecoder {
+		panic(r.Value)
+	}
+	return nil
+}
+
+func (r *panicRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	panic(r.Value)
+}
+
+type panicFilledRequest struct {
+	Path  struct{}
+	Query struct {
+		Name string
+	}
+
+	// write the response before panicking
+	Partial bool
+}
+
+func (r *panicFilledRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	if r.Partial {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("partial"))
+	}
+	panic("oops")
+}
+
+func (r *panicFilledRequest) HandleError(ctx context.Context, w http.ResponseWriter, err error) {
+	http.Error(w, "failed for "+r.Query.Name, http.StatusInternalServerError)
+}
+
+type panicWithoutHandlerRequest struct {
+	Path struct{}
+}
+
+func (r *panicWithoutHandlerRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	panic("oops")
+}
+
+type errorHandlerFunc func(context.Context, http.ResponseWriter, error)
+
+func (f errorHandlerFunc) HandleError(ctx context.Context, w http.ResponseWriter, err error) {
+	f(ctx, w, err)
+}
+
+type withoutEncoderRequest struct {
+	response.ErrorEncoder
+
+	Path     struct{}
+	Response struct {
+		Name string
+	}
+}
+
+func (r *withoutEncoderRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return nil
+}
+
+type tenantKey struct{}
+
+type interceptedRequest struct {
+	response.ErrorEncoder
+	response.JsonEncoder
+
+	Path     struct{}
+	Response struct {
+		Tenant string
+		Steps  []string
+	}
+}
+
+func (r *interceptedRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	r.Response.Tenant = tenant
+	r.Response.Steps = append(r.Response.Steps, "handle")
+	return nil
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string   { return "pet not found" }
+func (notFoundError) StatusCode() int { return http.StatusNotFound }
+
+type notFoundRequest struct {
+	response.ErrorEncoder
+
+	Path struct{}
+}
+
+func (r *notFoundRequest) Handle(ctx context.Context, w http.ResponseWriter) error {
+	return fmt.Errorf("loading pet: %w", notFoundError{})
+}
+
 func TestWrapper(t *testing.T) {
 	g := goblin.Goblin(t)
 
@@ -89,8 +531,12 @@ func TestWrapper(t *testing.T) {
 
 				Loc    loc
 				LocPtr *loc
+
+				Time     time.Time
+				Addr     netip.Addr
+				AddrPtr  *netip.Addr
+				Duration time.Duration
 			}
-			ctx := context.Background()
 
 			tests := []struct {
 				Field    string
@@ -124,6 +570,13 @@ func TestWrapper(t *testing.T) {
 
 				{"LocPtr", `{"Type": "toto"}`, loc{Type: "toto"}},
 				{"Loc", `{"Type": "titi"}`, loc{Type: "titi"}},
+
+				{"Time", "2023-04-05T10:20:30Z", time.Date(2023, 4, 5, 10, 20, 30, 0, time.UTC)},
+				{"Time", `"2023-04-05T10:20:30Z"`, time.Date(2023, 4, 5, 10, 20, 30, 0, time.UTC)},
+				{"Addr", "192.168.0.1", netip.MustParseAddr("192.168.0.1")},
+				{"AddrPtr", "::1", netip.MustParseAddr("::1")},
+				{"Duration", "1h30m", 90 * time.Minute},
+				{"Duration", "1000", time.Microsecond},
 			}
 
 			for _, tt := range tests {
@@ -133,7 +586,7 @@ func TestWrapper(t *testing.T) {
 					st := st{}
 					vv := reflect.ValueOf(&st).Elem().FieldByName(tt.Field)
 
-					err := setFValue(ctx, "unused", vv, tt.Value)
+					err := setFValue(vv, tt.Value)
 					require.NoError(g, err)
 
 					if strings.HasSuffix(tt.Field, "Ptr") {
@@ -162,6 +615,7 @@ func TestWrapper(t *testing.T) {
 					PascalCaseJsonTagField    *string `json:"overrided_name"`
 					Slice                     []string
 					Tag                       string `json:"tag,omitempty"`
+					Quoted                    int64  `json:"quoted,string"`
 				}
 
 				Header struct {
@@ -197,6 +651,7 @@ func TestWrapper(t *testing.T) {
 				query.Set("pascal_case_no_json_tag_field", "some_value_1")
 				query.Set("overrided_name", "some_value_2")
 				query.Set("tag", "some_tag_value")
+				query.Set("quoted", `"12"`)
 				slice = []string{"name", "duration", "label"}
 				query.Set("slice", strings.Join(slice, ","))
 
@@ -207,7 +662,7 @@ func TestWrapper(t *testing.T) {
 				}
 
 				parsingErrors := map[string]string{}
-				vv, hasResponse, err := createFilledRequestObject(req, m, parsingErrors)
+				vv, hasResponse, err := createFilledRequestObject(req, m, NewConfig(), parsingErrors)
 				require.NoError(g, err)
 
 				require.IsType(g, &testRequest{}, vv.Interface())
@@ -252,6 +707,10 @@ func TestWrapper(t *testing.T) {
 				require.Equal(g, "some_tag_value", reqObject.Query.Tag)
 			})
 
+			g.It("should parse quoted value for string encoded field", func() {
+				require.Equal(g, int64(12), reqObject.Query.Quoted)
+			})
+
 			g.It("should parse slice field", func() {
 				require.Equal(g, slice, reqObject.Query.Slice)
 			})
@@ -262,6 +721,85 @@ func TestWrapper(t *testing.T) {
 
 		})
 
+		g.Describe("enums", func() {
+			var config *Config
+
+			call := func(query string, body string) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("POST", "/?"+query, strings.NewReader(body)).WithContext(ctx)
+				w := httptest.NewRecorder()
+
+				WrapRequestWithConfig(&listPetsRequest{}, config)(w, r)
+				return w
+			}
+
+			g.BeforeEach(func() {
+				config = NewConfig()
+				config.Callbacks = shared.NewChipiCallbacks(&statusResolver{})
+			})
+
+			g.It("should accept valid values", func() {
+				w := call("status=sold&kinds=1,2", `{"pets": [{"status": "available"}]}`)
+				assert.Equal(g, http.StatusNoContent, w.Code)
+			})
+
+			g.It("should reject invalid query values", func() {
+				w := call("status=lost", "")
+				assert.Equal(g, http.StatusBadRequest, w.Code)
+				assert.JSONEq(g, `{
+					"request.query.status": "invalid value lost, expected one of: available, sold"
+				}`, w.Body.String())
+			})
+
+			g.It("should reject invalid values from types declaring their enum", func() {
+				w := call("kinds=1,3", "")
+				assert.Equal(g, http.StatusBadRequest, w.Code)
+				assert.Contains(g, w.Body.String(), "request.query.kinds")
+			})
+
+			g.It("should accept omitted optional body values", func() {
+				w := call("", `{"pets": [{"status": "sold"}, {"status": "available", "kind": 2}]}`)
+				assert.Equal(g, http.StatusNoContent, w.Code)
+			})
+
+			g.It("should reject omitted required body values", func() {
+				w := call("", `{"pets": [{"kind": 1}]}`)
+				assert.Equal(g, http.StatusBadRequest, w.Code)
+				assert.Contains(g, w.Body.String(), "request.body.pets[0].status")
+			})
+
+			g.It("should reject invalid body values", func() {
+				w := call("", `{"pets": [{"status": "available"}, {"status": "lost"}]}`)
+				assert.Equal(g, http.StatusBadRequest, w.Code)
+				assert.JSONEq(g, `{
+					"request.body.pets[1].status": "invalid value lost, expected one of: available, sold"
+				}`, w.Body.String())
+			})
+
+			g.It("should compare the converted values exactly", func() {
+				enum := shared.Enum{{Title: "Half", Value: 1.5}}
+				assert.False(g, enumContains(enum, reflect.ValueOf(petKind(1))))
+
+				enum = shared.Enum{{Title: "Dog", Value: 1.0}}
+				assert.True(g, enumContains(enum, reflect.ValueOf(petKind(1))))
+			})
+
+			g.It("should not panic on uncomparable values", func() {
+				enum := shared.Enum{{Title: "Empty", Value: []string{}}}
+				assert.False(g, enumContains(enum, reflect.ValueOf([]string{})))
+			})
+
+			g.It("should reject the uncomparable enum types", func() {
+				err := CheckEnumTypes(shared.NewChipiCallbacks(&statusResolver{}), &listPetsRequest{})
+				require.NoError(g, err)
+
+				err = CheckEnumTypes(shared.NewChipiCallbacks(nil), &uncomparableEnumRequest{})
+				require.Error(g, err)
+				assert.Contains(g, err.Error(), "is not comparable")
+			})
+		})
+
 		g.Describe("custom body decoder", func() {
 			g.It("should be called", func() {
 				rctx := chi.NewRouteContext()
@@ -280,6 +818,608 @@ func TestWrapper(t *testing.T) {
 				assert.JSONEq(g, `{"N": 0, "Str": "some great string !"}`, writtenbody.String())
 			})
 		})
+
+		g.Describe("multipart body decoder", func() {
+			var avatar string
+
+			call := func() *httptest.ResponseRecorder {
+				buf := &bytes.Buffer{}
+				mw := multipart.NewWriter(buf)
+
+				require.NoError(g, mw.WriteField("name", "john"))
+				require.NoError(g, mw.WriteField("tags", "a"))
+				require.NoError(g, mw.WriteField("tags", "b"))
+
+				files := [][2]string{
+					{"avatar", avatar},
+					{"photos", "first"},
+					{"photos", "second"},
+					{"resume", "my resume"},
+				}
+				for i, file := range files {
+					fw, err := mw.CreateFormFile(file[0], fmt.Sprintf("file%d.txt", i))
+					require.NoError(g, err)
+					_, err = fw.Write([]byte(file[1]))
+					require.NoError(g, err)
+				}
+				require.NoError(g, mw.Close())
+
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("POST", "/", buf).WithContext(ctx)
+				r.Header.Set("Content-Type", mw.FormDataContentType())
+
+				w := httptest.NewRecorder()
+				WrapRequest(&uploadRequest{})(w, r)
+				return w
+			}
+
+			g.BeforeEach(func() {
+				avatar = "small"
+			})
+
+			g.It("should fill fields and files", func() {
+				w := call()
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{
+					"name": "john",
+					"tags": ["a", "b"],
+					"avatar": "small",
+					"photos": ["file1.txt", "file2.txt"],
+					"resume": "my resume"
+				}`, w.Body.String())
+			})
+
+			g.It("should reject files larger than max-size", func() {
+				avatar = "this is too large"
+
+				w := call()
+				assert.Equal(g, http.StatusBadRequest, w.Code)
+				assert.Contains(g, w.Body.String(), `avatar: file \"file0.txt\" is too large`)
+			})
+		})
+
+		g.Describe("multiple body decoders", func() {
+			call := func(contentType string, body string) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("POST", "/", strings.NewReader(body)).WithContext(ctx)
+				if contentType != "" {
+					r.Header.Set("Content-Type", contentType)
+				}
+
+				w := httptest.NewRecorder()
+				WrapRequest(&multiDecodersRequest{})(w, r)
+				return w
+			}
+
+			g.It("should use the decoder matching the content type", func() {
+				w := call("application/json; charset=utf-8", `{"name": "json"}`)
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"name": "json"}`, w.Body.String())
+
+				w = call("application/x-www-form-urlencoded", `name=form`)
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"name": "form"}`, w.Body.String())
+			})
+
+			g.It("should accept an empty body without content type", func() {
+				w := call("", "")
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"name": ""}`, w.Body.String())
+			})
+
+			g.It("should reject other content types", func() {
+				w := call("application/xml", `<name>xml</name>`)
+				assert.Equal(g, http.StatusUnsupportedMediaType, w.Code)
+				assert.JSONEq(g, `{
+					"request.body": "unsupported media type: \"application/xml\", expected one of: application/json, application/x-www-form-urlencoded"
+				}`, w.Body.String())
+			})
+		})
+
+		g.Describe("response negotiation", func() {
+			call := func(accept string) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+				if accept != "" {
+					r.Header.Set("Accept", accept)
+				}
+
+				w := httptest.NewRecorder()
+				WrapRequest(&negotiatedRequest{})(w, r)
+				return w
+			}
+
+			g.It("should use json by default", func() {
+				for _, accept := range []string{"", "*/*"} {
+					w := call(accept)
+					require.Equal(g, http.StatusOK, w.Code)
+					assert.Equal(g, "application/json", w.Header().Get("Content-Type"))
+					assert.Equal(g, "Accept", w.Header().Get("Vary"))
+					assert.JSONEq(g, `[{"name": "rex", "age": 3}, {"name": "felix", "age": 5}]`, w.Body.String())
+				}
+			})
+
+			g.It("should use the preferred content type", func() {
+				w := call("application/json;q=0.5, application/xml")
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.Equal(g, "application/xml", w.Header().Get("Content-Type"))
+				assert.Equal(g, xml.Header+
+					`<items><item><name>rex</name><age>3</age></item><item><name>felix</name><age>5</age></item></items>`,
+					w.Body.String())
+
+				w = call("text/*")
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.Equal(g, "text/csv", w.Header().Get("Content-Type"))
+				assert.Equal(g, "name,age\nrex,3\nfelix,5\n", w.Body.String())
+			})
+
+			g.It("should return a 406 if no content type is acceptable", func() {
+				w := call("application/msgpack, text/csv;q=0")
+				assert.Equal(g, http.StatusNotAcceptable, w.Code)
+				assert.Contains(g, w.Body.String(), "request.header.Accept")
+			})
+		})
+
+		g.Describe("server-sent events", func() {
+			call := func(ctx context.Context, req *eventsRequest) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+
+				w := httptest.NewRecorder()
+				WrapRequest(req)(w, r)
+				return w
+			}
+
+			g.It("should send events", func() {
+				ch := make(chan negotiatedPet, 2)
+				ch <- negotiatedPet{Name: "rex", Age: 3}
+				ch <- negotiatedPet{Name: "felix", Age: 5}
+				close(ch)
+
+				w := call(context.Background(), &eventsRequest{
+					Producer: func(ctx context.Context, send func(response.Event[negotiatedPet]) error) error {
+						err := send(response.Event[negotiatedPet]{ID: "1", Name: "created", Data: negotiatedPet{Name: "medor"}})
+						if err != nil {
+							return err
+						}
+
+						return response.NewChannelStream(ch).Run(ctx, func(ev response.StreamEvent) error {
+							return send(response.Event[negotiatedPet]{Data: ev.Data.(negotiatedPet)})
+						})
+					},
+				})
+
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.Equal(g, "text/event-stream", w.Header().Get("Content-Type"))
+				assert.Equal(g, "no-cache", w.Header().Get("Cache-Control"))
+				assert.Equal(g, "id: 1\nevent: created\ndata: {\"name\":\"medor\",\"age\":0}\n\n"+
+					"data: {\"name\":\"rex\",\"age\":3}\n\n"+
+					"data: {\"name\":\"felix\",\"age\":5}\n\n",
+					w.Body.String())
+			})
+
+			g.It("should send heartbeats", func() {
+				w := call(context.Background(), &eventsRequest{
+					SSEEncoder: response.SSEEncoder{Heartbeat: 5 * time.Millisecond},
+					Producer: func(ctx context.Context, send func(response.Event[negotiatedPet]) error) error {
+						time.Sleep(30 * time.Millisecond)
+						return nil
+					},
+				})
+
+				assert.Contains(g, w.Body.String(), ": heartbeat\n\n")
+			})
+
+			g.It("should stop when the client is gone", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(10*time.Millisecond, cancel)
+
+				w := call(ctx, &eventsRequest{
+					Producer: func(ctx context.Context, send func(response.Event[negotiatedPet]) error) error {
+						err := send(response.Event[negotiatedPet]{Data: negotiatedPet{Name: "rex"}})
+						if err != nil {
+							return err
+						}
+
+						<-ctx.Done()
+						return ctx.Err()
+					},
+				})
+
+				assert.Equal(g, "data: {\"name\":\"rex\",\"age\":0}\n\n", w.Body.String())
+			})
+
+			g.It("should send an error event", func() {
+				w := call(context.Background(), &eventsRequest{
+					Producer: func(ctx context.Context, send func(response.Event[negotiatedPet]) error) error {
+						return errors.New("database is down")
+					},
+				})
+
+				assert.Equal(g, "event: error\ndata: \"database is down\"\n\n", w.Body.String())
+			})
+		})
+
+		g.Describe("sequences", func() {
+			call := func(accept string, req interface{}) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+				r.Header.Set("Accept", accept)
+
+				w := httptest.NewRecorder()
+				WrapRequest(req)(w, r)
+				return w
+			}
+
+			g.It("should stream a json array", func() {
+				w := call("application/json", &exportRequest{Count: 3})
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `[
+					{"name": "pet0", "age": 0},
+					{"name": "pet1", "age": 1},
+					{"name": "pet2", "age": 2}
+				]`, w.Body.String())
+
+				w = call("application/json", &exportRequest{Count: 0})
+				assert.JSONEq(g, `[]`, w.Body.String())
+			})
+
+			g.It("should stream ndjson", func() {
+				w := call("application/x-ndjson", &exportRequest{Count: 2})
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.Equal(g, "application/x-ndjson", w.Header().Get("Content-Type"))
+				assert.Equal(g, "{\"name\":\"pet0\",\"age\":0}\n{\"name\":\"pet1\",\"age\":1}\n", w.Body.String())
+			})
+
+			g.It("should stream csv", func() {
+				w := call("text/csv", &exportRequest{Count: 250})
+				require.Equal(g, http.StatusOK, w.Code)
+
+				lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+				require.Len(g, lines, 251)
+				assert.Equal(g, "name,age", lines[0])
+				assert.Equal(g, "pet249,249", lines[250])
+			})
+
+			g.It("should stream channels", func() {
+				ch := make(chan negotiatedPet, 2)
+				ch <- negotiatedPet{Name: "rex", Age: 3}
+				ch <- negotiatedPet{Name: "felix", Age: 5}
+				close(ch)
+
+				w := httptest.NewRecorder()
+				(&response.JsonEncoder{}).EncodeResponse(context.Background(), w, (<-chan negotiatedPet)(ch))
+				assert.JSONEq(g, `[{"name": "rex", "age": 3}, {"name": "felix", "age": 5}]`, w.Body.String())
+			})
+		})
+
+		g.Describe("body safeguards", func() {
+			call := func(req interface{}, config *Config, body io.Reader) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("POST", "/", body).WithContext(ctx)
+
+				w := httptest.NewRecorder()
+				WrapRequestWithConfig(req, config)(w, r)
+				return w
+			}
+
+			g.It("should accept bodies within the limit", func() {
+				w := call(&limitedRequest{}, NewConfig(), strings.NewReader(`{"name": "rex"}`))
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"name": "rex", "tags": null}`, w.Body.String())
+			})
+
+			g.It("should reject bodies larger than the max-size tag", func() {
+				w := call(&limitedRequest{}, NewConfig(), strings.NewReader(`{"name": "a name much longer than allowed"}`))
+				assert.Equal(g, http.StatusRequestEntityTooLarge, w.Code)
+				assert.Contains(g, w.Body.String(), "request body too large")
+			})
+
+			g.It("should reject bodies without content length", func() {
+				// hide the length
+				body := io.MultiReader(strings.NewReader(`{"name": "a name much longer than allowed"}`))
+
+				w := call(&limitedRequest{}, NewConfig(), body)
+				assert.Equal(g, http.StatusRequestEntityTooLarge, w.Code)
+				assert.Contains(g, w.Body.String(), "request body too large")
+			})
+
+			g.It("should use the size from the config", func() {
+				config := NewConfig()
+				config.MaxBodySize = 8
+
+				w := call(&createTestUser{}, config, strings.NewReader(`{"N": 42, "Str": "some"}`))
+				assert.Equal(g, http.StatusRequestEntityTooLarge, w.Code)
+			})
+
+			g.It("should reject trailing data", func() {
+				w := call(&limitedRequest{}, NewConfig(), strings.NewReader(`{"name": "rex"} {}`))
+				assert.Equal(g, http.StatusBadRequest, w.Code)
+				assert.Contains(g, w.Body.String(), "unexpected data after json body")
+			})
+
+			g.It("should reject unknown fields if asked to", func() {
+				w := call(&limitedRequest{}, NewConfig(), strings.NewReader(`{"age": 3}`))
+				assert.Equal(g, http.StatusOK, w.Code)
+
+				w = call(&limitedRequest{
+					JsonBodyDecoder: request.JsonBodyDecoder{DisallowUnknownFields: true},
+				}, NewConfig(), strings.NewReader(`{"age": 3}`))
+				assert.Equal(g, http.StatusBadRequest, w.Code)
+				assert.Contains(g, w.Body.String(), `unknown field \"age\"`)
+			})
+
+			g.It("should reject bodies nested too deeply", func() {
+				req := &limitedRequest{
+					JsonBodyDecoder: request.JsonBodyDecoder{MaxDepth: 3},
+				}
+
+				w := call(req, NewConfig(), strings.NewReader(`{"tags": [["a"]]}`))
+				assert.Equal(g, http.StatusOK, w.Code)
+
+				w = call(req, NewConfig(), strings.NewReader(`{"tags": [[["a"]]]}`))
+				assert.Equal(g, http.StatusBadRequest, w.Code)
+				assert.Contains(g, w.Body.String(), "nested too deeply")
+			})
+		})
+
+		g.Describe("panics", func() {
+			var tracer *recordingTracer
+
+			call := func(req interface{}, config *Config) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("POST", "/", strings.NewReader("{}")).WithContext(ctx)
+
+				w := httptest.NewRecorder()
+				WrapRequestWithConfig(req, config)(w, r)
+				return w
+			}
+
+			g.BeforeEach(func() {
+				tracer = useRecordingTracer()
+			})
+
+			g.It("should send the panic to the error handler", func() {
+				w := call(&panicRequest{Value: "oops"}, NewConfig())
+				assert.Equal(g, http.StatusInternalServerError, w.Code)
+				assert.Equal(g, "Internal Server Error\n", w.Body.String())
+
+				span := tracer.span("panicRequest")
+				require.NotNil(g, span)
+				assert.Equal(g, codes.Error, span.status)
+				require.Len(g, span.errors, 1)
+				assert.Equal(g, "panic: oops", span.errors[0].Error())
+
+				stack, found := span.attribute("exception.stacktrace")
+				require.True(g, found)
+				assert.Contains(g, stack.AsString(), "panicRequest")
+			})
+
+			g.It("should send the panic to the filled request object", func() {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("GET", "/?name=john", nil).WithContext(ctx)
+
+				w := httptest.NewRecorder()
+				WrapRequestWithConfig(&panicFilledRequest{}, NewConfig())(w, r)
+
+				assert.Equal(g, http.StatusInternalServerError, w.Code)
+				assert.Equal(g, "failed for john\n", w.Body.String())
+			})
+
+			g.It("should not write an error after the response was started", func() {
+				w := call(&panicFilledRequest{Partial: true}, NewConfig())
+				assert.Equal(g, http.StatusAccepted, w.Code)
+				assert.Equal(g, "partial", w.Body.String())
+			})
+
+			g.It("should recover panics in decoders", func() {
+				w := call(&panicRequest{Value: errors.New("broken"), PanicInDecoder: true}, NewConfig())
+				assert.Equal(g, http.StatusInternalServerError, w.Code)
+			})
+
+			g.It("should use the default error handler", func() {
+				var received error
+
+				config := NewConfig()
+				config.ErrorHandler = errorHandlerFunc(func(ctx context.Context, w http.ResponseWriter, err error) {
+					received = err
+					w.WriteHeader(http.StatusTeapot)
+				})
+
+				w := call(&panicWithoutHandlerRequest{}, config)
+				assert.Equal(g, http.StatusTeapot, w.Code)
+
+				var panicErr *PanicError
+				require.ErrorAs(g, received, &panicErr)
+				assert.Equal(g, "oops", panicErr.Value)
+				assert.NotEmpty(g, panicErr.Stack)
+			})
+
+			g.It("should panic again if asked to", func() {
+				config := NewConfig()
+				config.Repanic = true
+
+				assert.PanicsWithValue(g, "oops", func() {
+					call(&panicRequest{Value: "oops"}, config)
+				})
+
+				span := tracer.span("panicRequest")
+				require.NotNil(g, span)
+				assert.Equal(g, codes.Error, span.status)
+			})
+
+			g.It("should not recover aborted handlers", func() {
+				assert.PanicsWithValue(g, http.ErrAbortHandler, func() {
+					call(&panicRequest{Value: http.ErrAbortHandler}, NewConfig())
+				})
+			})
+		})
+
+		g.Describe("defaults", func() {
+			call := func(req interface{}, config *Config) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+
+				w := httptest.NewRecorder()
+				WrapRequestWithConfig(req, config)(w, r)
+				return w
+			}
+
+			g.It("should report a missing encoder", func() {
+				w := call(&withoutEncoderRequest{}, NewConfig())
+				assert.Equal(g, http.StatusInternalServerError, w.Code)
+			})
+
+			g.It("should use the default encoder", func() {
+				config := NewConfig()
+				config.ResponseEncoder = &response.JsonEncoder{}
+
+				w := call(&withoutEncoderRequest{}, config)
+				assert.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"Name": ""}`, w.Body.String())
+			})
+
+			g.It("should report errors without error handler", func() {
+				w := call(&panicWithoutHandlerRequest{}, NewConfig())
+				assert.Equal(g, http.StatusInternalServerError, w.Code)
+			})
+		})
+
+		g.Describe("interceptors", func() {
+			call := func(req interface{}, config *Config, interceptors ...Interceptor) *httptest.ResponseRecorder {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+
+				w := httptest.NewRecorder()
+				WrapRequestWithConfig(req, config, interceptors...)(w, r)
+				return w
+			}
+
+			step := func(name string) Interceptor {
+				return Intercept(func(ctx context.Context, req *interceptedRequest, call *Call, next HandleFunc) error {
+					req.Response.Steps = append(req.Response.Steps, name)
+					return next(ctx, call)
+				})
+			}
+
+			g.It("should run the config interceptors first", func() {
+				config := NewConfig()
+				config.Interceptors = []Interceptor{step("config")}
+
+				w := call(&interceptedRequest{}, config, step("operation"))
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"Tenant": "", "Steps": ["config", "operation", "handle"]}`, w.Body.String())
+			})
+
+			g.It("should decorate the context", func() {
+				w := call(&interceptedRequest{}, NewConfig(), func(ctx context.Context, call *Call, next HandleFunc) error {
+					return next(context.WithValue(ctx, tenantKey{}, "acme"), call)
+				})
+
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"Tenant": "acme", "Steps": ["handle"]}`, w.Body.String())
+			})
+
+			g.It("should stop the request", func() {
+				w := call(&interceptedRequest{}, NewConfig(), func(ctx context.Context, call *Call, next HandleFunc) error {
+					return notFoundError{}
+				})
+
+				assert.Equal(g, http.StatusNotFound, w.Code)
+				assert.Equal(g, "pet not found\n", w.Body.String())
+			})
+
+			g.It("should change the response", func() {
+				w := call(&interceptedRequest{}, NewConfig(), func(ctx context.Context, call *Call, next HandleFunc) error {
+					err := next(ctx, call)
+					if err != nil {
+						return err
+					}
+
+					resp, ok := call.Response().(*struct {
+						Tenant string
+						Steps  []string
+					})
+					require.True(g, ok)
+					resp.Tenant = "changed"
+					return nil
+				})
+
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"Tenant": "changed", "Steps": ["handle"]}`, w.Body.String())
+			})
+
+			g.It("should skip typed interceptors for other requests", func() {
+				called := false
+				w := call(&notFoundRequest{}, NewConfig(), Intercept(func(ctx context.Context, req *interceptedRequest, call *Call, next HandleFunc) error {
+					called = true
+					return next(ctx, call)
+				}))
+
+				assert.Equal(g, http.StatusNotFound, w.Code)
+				assert.False(g, called)
+			})
+		})
+
+		g.Describe("error encoder", func() {
+			g.It("should use the status of the error", func() {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+
+				w := httptest.NewRecorder()
+				WrapRequest(&notFoundRequest{})(w, r)
+
+				assert.Equal(g, http.StatusNotFound, w.Code)
+				assert.Equal(g, "loading pet: pet not found\n", w.Body.String())
+			})
+		})
+
+		g.Describe("form body decoder", func() {
+			g.It("should fill fields", func() {
+				rctx := chi.NewRouteContext()
+				ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+				r := httptest.NewRequest("POST", "/", strings.NewReader("name=john&age=42&nick=jo")).WithContext(ctx)
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+				w := httptest.NewRecorder()
+				WrapRequest(&formRequest{})(w, r)
+
+				require.Equal(g, http.StatusOK, w.Code)
+				assert.JSONEq(g, `{"name": "john", "age": 42, "nickname": "jo"}`, w.Body.String())
+			})
+		})
+
+		g.Describe("body files", func() {
+			g.It("should close the io.Reader files", func() {
+				first, second := &closingReader{}, &closingReader{}
+
+				obj := &struct {
+					Body struct {
+						Resume io.Reader
+						Photos []io.Reader
+						Empty  io.Reader
+					}
+				}{}
+				obj.Body.Resume = first
+				obj.Body.Photos = []io.Reader{second}
+
+				closeBodyFiles(reflect.ValueOf(obj))
+
+				assert.True(g, first.closed)
+				assert.True(g, second.closed)
+			})
+		})
 	})
 }
 
@@ -303,7 +1443,7 @@ func BenchmarkDecoding(b *testing.B) {
 		b.Run("reflect", func(b *testing.B) {
 			typ := reflect.TypeOf(n)
 			for i := 0; i < b.N; i++ {
-				_, err := convertValue(typ, "42")
+				_, err := shared.ConvertValue(typ, "42")
 				if err != nil {
 					b.Fatalf("err: %s", err.Error())
 				}